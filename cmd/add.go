@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SisyphusSQ/go-web-starter/internal/scaf_fold"
+)
+
+var (
+	addFieldsFlag string
+	addDBFlag     string
+	addDirFlag    string
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add code to a generated project",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+var addResourceCmd = &cobra.Command{
+	Use:   "resource <name>",
+	Short: "Add a CRUD resource with do, vo, repository, service, handler and DDL",
+	Example: `  go-web-starter add resource order --fields "name:string,amount:int64"
+  go-web-starter add resource order_item --fields "sku:string,price:float64,paid_at:time" --db postgres`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fields, err := scaf_fold.ParseResourceFields(addFieldsFlag)
+		if err != nil {
+			return err
+		}
+
+		result, err := scaf_fold.AddResource(addDirFlag, scaf_fold.ResourceSpec{
+			Name:   args[0],
+			Fields: fields,
+			DB:     addDBFlag,
+		})
+		if err != nil {
+			return fmt.Errorf("add resource: %w", err)
+		}
+
+		printResourceResult(cmd.OutOrStdout(), args[0], result)
+		return nil
+	},
+}
+
+func initAdd() {
+	addResourceCmd.Flags().StringVar(
+		&addFieldsFlag,
+		"fields",
+		"",
+		"Comma-separated name:type columns, types: string,int,int32,int64,float64,bool,time",
+	)
+	addResourceCmd.Flags().StringVar(
+		&addDBFlag,
+		"db",
+		"",
+		"SQL engine of the project: mysql, postgres or sqlite (default: detected from go.mod)",
+	)
+	addResourceCmd.Flags().StringVar(
+		&addDirFlag,
+		"dir",
+		".",
+		"Project directory",
+	)
+	_ = addResourceCmd.MarkFlagRequired("fields")
+
+	addCmd.AddCommand(addResourceCmd)
+	rootCmd.AddCommand(addCmd)
+}
+
+func printResourceResult(w io.Writer, name string, result scaf_fold.ResourceResult) {
+	fmt.Fprintf(w, "Resource %s added (%s)\n\n", name, result.DB)
+	for _, p := range result.Created {
+		fmt.Fprintf(w, "  create  %s\n", p)
+	}
+	for _, p := range result.Updated {
+		fmt.Fprintf(w, "  update  %s\n", p)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Next steps:")
+	fmt.Fprintln(w, "  # apply the new DDL in docs/schema (sqlite applies it at startup)")
+	fmt.Fprintln(w, "  go build ./...")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SisyphusSQ/go-web-starter/internal/scaf_fold"
+)
+
+// applyConflictFlag sets how opts treats existing files from --on-conflict;
+// prompt asks on the command's input for every conflicting file.
+func applyConflictFlag(cmd *cobra.Command, value string, opts *scaf_fold.Options) error {
+	strategy, err := scaf_fold.ParseConflictStrategy(value)
+	if err != nil {
+		return err
+	}
+	opts.OnConflict = strategy
+	if strategy == scaf_fold.ConflictPrompt {
+		opts.Confirm = confirmOverwrite(cmd.InOrStdin(), cmd.OutOrStdout())
+	}
+	return nil
+}
+
+// confirmOverwrite returns a Confirm callback that asks y/N on in until it
+// gets an answer.
+func confirmOverwrite(in io.Reader, out io.Writer) func(string) (bool, error) {
+	reader := bufio.NewReader(in)
+	return func(relPath string) (bool, error) {
+		for {
+			fmt.Fprintf(out, "%s already exists with different content. Overwrite? [y/N]: ", relPath)
+			line, err := reader.ReadString('\n')
+			if err != nil && (err != io.EOF || line == "") {
+				fmt.Fprintln(out)
+				return false, fmt.Errorf("read answer for %s: %w", relPath, err)
+			}
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "y", "yes":
+				return true, nil
+			case "", "n", "no":
+				return false, nil
+			}
+			fmt.Fprintln(out, "  please answer y or n")
+		}
+	}
+}
+
+func printGenerateResult(w io.Writer, result scaf_fold.GenerateResult) {
+	for _, p := range result.Created {
+		fmt.Fprintf(w, "  create     %s\n", p)
+	}
+	for _, p := range result.Overwritten {
+		fmt.Fprintf(w, "  overwrite  %s (previous content kept in %s)\n", p, result.Backups[p])
+	}
+	for _, p := range result.Skipped {
+		fmt.Fprintf(w, "  skip       %s\n", p)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SisyphusSQ/go-web-starter/internal/scaf_fold"
+)
+
+var (
+	diffDirFlag         string
+	diffTemplateDirFlag string
+	diffOverlayFlag     []string
+	diffNameOnlyFlag    bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show how a generated project differs from the templates",
+	Long: `diff re-renders the templates with the options recorded in .go-web-starter.json
+and prints a unified diff from the rendered templates to the working tree,
+grouped into files modified locally, files changed upstream and missing files.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, err := scaf_fold.Diff(diffDirFlag, scaf_fold.Options{
+			TemplateDir: diffTemplateDirFlag,
+			Overlays:    diffOverlayFlag,
+		})
+		if err != nil {
+			return fmt.Errorf("diff project: %w", err)
+		}
+
+		printDriftReport(cmd.OutOrStdout(), report, diffNameOnlyFlag)
+		return nil
+	},
+}
+
+func initDiff() {
+	diffCmd.Flags().StringVar(
+		&diffDirFlag,
+		"dir",
+		".",
+		"Project directory",
+	)
+	diffCmd.Flags().StringVar(
+		&diffTemplateDirFlag,
+		"template-dir",
+		"",
+		"Load templates from this directory (default: the one recorded in the lockfile)",
+	)
+	diffCmd.Flags().StringArrayVar(
+		&diffOverlayFlag,
+		"overlay",
+		nil,
+		"Template directory layered on top of the templates (repeatable, later wins; default: the recorded overlays)",
+	)
+	diffCmd.Flags().BoolVar(
+		&diffNameOnlyFlag,
+		"name-only",
+		false,
+		"List the files of each group without their diffs",
+	)
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+func printDriftReport(w io.Writer, report scaf_fold.DriftReport, nameOnly bool) {
+	fmt.Fprintf(
+		w,
+		"Project generated by %s, compared with %s\n",
+		report.LockedVersion,
+		report.StarterVersion,
+	)
+	if report.Empty() {
+		fmt.Fprintln(w, "\nNo differences")
+		return
+	}
+
+	printed := make(map[string]bool)
+	for _, group := range []struct {
+		title string
+		files []scaf_fold.FileDrift
+	}{
+		{"Modified locally", report.ModifiedLocally},
+		{"Changed upstream", report.ChangedUpstream},
+		{"Missing", report.Missing},
+	} {
+		if len(group.files) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n## %s (%d)\n", group.title, len(group.files))
+		for _, file := range group.files {
+			if nameOnly {
+				fmt.Fprintf(w, "  %s\n", file.Path)
+				continue
+			}
+			if printed[file.Path] {
+				fmt.Fprintf(w, "\n%s: see above\n", file.Path)
+				continue
+			}
+			printed[file.Path] = true
+			fmt.Fprintf(w, "\n%s", file.Diff)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SisyphusSQ/go-web-starter/internal/scaf_fold"
+)
+
+// hookRunner runs the commands of the post-generation hooks; tests replace
+// it to record them.
+var hookRunner scaf_fold.CommandRunner = scaf_fold.ExecRunner{}
+
+// resolveHooks returns the hooks selected by --hooks and --no-hooks.
+func resolveHooks(cmd *cobra.Command, hooksValue string, noHooks bool) ([]string, error) {
+	if noHooks {
+		if cmd.Flags().Changed("hooks") {
+			return nil, fmt.Errorf("--hooks and --no-hooks cannot be combined")
+		}
+		return nil, nil
+	}
+	return scaf_fold.ParseHooksFlag(hooksValue)
+}
+
+// runHooks runs hooks in projectDir after the project has been generated and
+// reports whether go mod tidy ran. The git hook commits only the files
+// written by the generator.
+func runHooks(cmd *cobra.Command, projectDir string, hooks []string, generated scaf_fold.GenerateResult) (bool, error) {
+	if len(hooks) == 0 {
+		return false, nil
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Running post-generation hooks: %s\n", strings.Join(hooks, ","))
+	paths := append(append([]string(nil), generated.Created...), generated.Overwritten...)
+	results, err := scaf_fold.RunHooks(cmd.Context(), projectDir, hooks, paths, hookRunner)
+	tidied := false
+	for _, result := range results {
+		if result.Skipped {
+			fmt.Fprintf(out, "  %-5s skipped (%s)\n", result.Hook, result.Reason)
+			continue
+		}
+		fmt.Fprintf(out, "  %-5s %s\n", result.Hook, strings.Join(result.Commands, " && "))
+		tidied = tidied || result.Hook == scaf_fold.HookTidy
+	}
+	fmt.Fprintln(out)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return tidied, fmt.Errorf("project generated, but post-generation %w", err)
+	}
+	return tidied, nil
+}
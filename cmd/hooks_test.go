@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordingRunnerForTest records the hook commands instead of running them.
+type recordingRunnerForTest struct {
+	commands []string
+	fail     string
+}
+
+func (r *recordingRunnerForTest) Run(_ context.Context, dir string, name string, args ...string) ([]byte, error) {
+	line := strings.Join(append([]string{name}, args...), " ")
+	r.commands = append(r.commands, filepath.Base(dir)+": "+line)
+	if r.fail != "" && strings.HasPrefix(line, r.fail) {
+		return []byte("go: network unreachable\n"), errors.New("exit status 1")
+	}
+	return nil, nil
+}
+
+func TestRootExecuteNewRunsNoHooksByDefault(t *testing.T) {
+	outDir := filepath.Join(t.TempDir(), "hooked")
+	var output bytes.Buffer
+	if err := executeRootForTest(&output, "new", outDir); err != nil {
+		t.Fatalf("execute new failed: %v", err)
+	}
+
+	if runner := hookRunner.(*recordingRunnerForTest); len(runner.commands) != 0 {
+		t.Fatalf("hook commands = %q, want none", runner.commands)
+	}
+	if strings.Contains(output.String(), "Running post-generation hooks") {
+		t.Fatalf("output reports hooks that were not selected:\n%s", output.String())
+	}
+	for _, want := range []string{"Project generated at " + outDir + "\n", "Next steps:\n  cd " + outDir + "\n"} {
+		if !strings.Contains(output.String(), want) {
+			t.Fatalf("output misses %q:\n%s", want, output.String())
+		}
+	}
+}
+
+func TestRootExecuteNewGitHookCommitsGeneratedFilesOnly(t *testing.T) {
+	outDir := filepath.Join(t.TempDir(), "hooked")
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(outDir))
+	writeFileForTest(t, filepath.Join(outDir, "notes.txt"), "mine\n")
+
+	var output bytes.Buffer
+	if err := executeRootForTest(&output, "new", outDir, "--hooks", "tidy,fmt,git"); err != nil {
+		t.Fatalf("execute new failed: %v", err)
+	}
+
+	runner := hookRunner.(*recordingRunnerForTest)
+	if len(runner.commands) != 5 ||
+		runner.commands[2] != "hooked: git init" ||
+		runner.commands[4] != "hooked: git commit -m Initial commit from go-web-starter v0.1.0" {
+		t.Fatalf("hook commands = %q", runner.commands)
+	}
+	add := runner.commands[3]
+	for _, want := range []string{"hooked: git add -- ", " go.mod ", " .go-web-starter.json"} {
+		if !strings.Contains(add, want) {
+			t.Fatalf("git add = %q, want containing %q", add, want)
+		}
+	}
+	if strings.Contains(add, "notes.txt") {
+		t.Fatalf("git add stages a file that was not generated: %q", add)
+	}
+	if !strings.Contains(output.String(), "git add -- <") {
+		t.Fatalf("output does not summarize git add:\n%s", output.String())
+	}
+}
+
+func TestRootExecuteNewSelectsHooks(t *testing.T) {
+	if err := executeRootForTest(nil, "new", filepath.Join(t.TempDir(), "no-hooks"), "--no-hooks"); err != nil {
+		t.Fatalf("execute new --no-hooks failed: %v", err)
+	}
+	if runner := hookRunner.(*recordingRunnerForTest); len(runner.commands) != 0 {
+		t.Fatalf("--no-hooks ran commands: %q", runner.commands)
+	}
+
+	if err := executeRootForTest(nil, "new", filepath.Join(t.TempDir(), "fmt-only"), "--hooks", "fmt"); err != nil {
+		t.Fatalf("execute new --hooks fmt failed: %v", err)
+	}
+	runner := hookRunner.(*recordingRunnerForTest)
+	if len(runner.commands) != 1 || !strings.HasPrefix(runner.commands[0], "fmt-only: gofmt -l -w ") ||
+		!strings.Contains(runner.commands[0], " "+filepath.Join("app", "main.go")) ||
+		strings.Contains(runner.commands[0], " .") || strings.Contains(runner.commands[0], "go.mod") {
+		t.Fatalf("--hooks fmt ran %q, want gofmt on the generated Go files", runner.commands)
+	}
+
+	err := executeRootForTest(nil, "new", filepath.Join(t.TempDir(), "bad"), "--hooks", "lint")
+	if err == nil || !strings.Contains(err.Error(), `invalid hook "lint"`) {
+		t.Fatalf("execute new --hooks lint error = %v", err)
+	}
+	err = executeRootForTest(nil, "new", filepath.Join(t.TempDir(), "both"), "--hooks", "git", "--no-hooks")
+	if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Fatalf("execute new --hooks --no-hooks error = %v", err)
+	}
+}
+
+func TestRootExecuteNewReportsHookFailure(t *testing.T) {
+	ensureInitialized()
+	outDir := filepath.Join(t.TempDir(), "offline")
+	resetCLIFlagStateForTest()
+	runner := &recordingRunnerForTest{fail: "go mod tidy"}
+	hookRunner = runner
+	rootCmd.SetArgs([]string{"new", outDir, "--hooks", "tidy,fmt"})
+	rootCmd.SetOut(bytes.NewBuffer(nil))
+	rootCmd.SetErr(bytes.NewBuffer(nil))
+
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "hook tidy: go mod tidy") ||
+		!strings.Contains(err.Error(), "network unreachable") {
+		t.Fatalf("execute new error = %v, want tidy failure with its output", err)
+	}
+	if len(runner.commands) != 1 {
+		t.Fatalf("hooks after the failing one ran: %q", runner.commands)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "go.mod")); err != nil {
+		t.Fatalf("project should stay generated after a hook failure: %v", err)
+	}
+}
+
+func TestRootExecuteInitSkipsGitHookInRepository(t *testing.T) {
+	projectDir := filepath.Join(t.TempDir(), "existing")
+	if err := os.MkdirAll(filepath.Join(projectDir, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir .git: %v", err)
+	}
+	originalWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("get current directory: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(originalWD)
+	})
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatalf("chdir project dir: %v", err)
+	}
+
+	var output bytes.Buffer
+	if err := executeRootForTest(&output, "init", "--hooks", "git,tidy"); err != nil {
+		t.Fatalf("execute init failed: %v", err)
+	}
+	runner := hookRunner.(*recordingRunnerForTest)
+	if !reflect.DeepEqual(runner.commands, []string{".: go mod tidy"}) {
+		t.Fatalf("hook commands = %q", runner.commands)
+	}
+	if !strings.Contains(output.String(), "git   skipped (.git already exists)") {
+		t.Fatalf("output does not report the skipped git hook:\n%s", output.String())
+	}
+	if !strings.Contains(output.String(), "Project initialized in current directory\n") ||
+		!strings.Contains(output.String(), "Next steps:\n") {
+		t.Fatalf("output misses the init summary:\n%s", output.String())
+	}
+}
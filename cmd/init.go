@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -9,33 +10,93 @@ import (
 )
 
 var (
-	initModuleNameFlag string
-	initBinaryNameFlag string
-	initDBFlag         string
+	initModuleNameFlag  string
+	initBinaryNameFlag  string
+	initDBFlag          string
+	initDryRunFlag      bool
+	initTemplateDirFlag string
+	initOverlayFlag     []string
+	initWithFlag        string
+	initWithoutFlag     string
+	initConfigFlag      string
+	initPresetFlag      string
+	initSetFlag         []string
+	initSetFileFlag     []string
+	initHooksFlag       string
+	initNoHooksFlag     bool
+	initOnConflictFlag  string
+	initNoModuleFlag    bool
 )
 
 var initCmd = &cobra.Command{
 	Use:   "init",
-	Short: "Initialize a project in current directory (.git allowed)",
+	Short: "Initialize a project in current directory",
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		data, err := buildTemplateData(
-			".",
-			initModuleNameFlag,
-			initBinaryNameFlag,
-			initDBFlag,
+		var (
+			data scaf_fold.TemplateData
+			opts scaf_fold.Options
+			err  error
 		)
+		if shouldRunWizard(cmd) {
+			data, err = runWizard(cmd.InOrStdin(), cmd.OutOrStdout(), ".")
+		} else {
+			data, opts, err = resolveGenerator(cmd, ".", generatorFlags{
+				module:      initModuleNameFlag,
+				binary:      initBinaryNameFlag,
+				db:          initDBFlag,
+				with:        initWithFlag,
+				without:     initWithoutFlag,
+				templateDir: initTemplateDirFlag,
+				overlays:    initOverlayFlag,
+				config:      initConfigFlag,
+				preset:      initPresetFlag,
+				sets:        initSetFlag,
+				setFiles:    initSetFileFlag,
+				noModule:    initNoModuleFlag,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		if err := applyConflictFlag(cmd, initOnConflictFlag, &opts); err != nil {
+			return err
+		}
+		hooks, err := resolveHooks(cmd, initHooksFlag, initNoHooksFlag)
 		if err != nil {
 			return err
 		}
+		ws, err := scaf_fold.FindWorkspace(".")
+		if err != nil {
+			return err
+		}
+
+		if initDryRunFlag {
+			entries, err := scaf_fold.PlanWithOptions(data, opts)
+			if err != nil {
+				return fmt.Errorf("plan project: %w", err)
+			}
+			printPlan(cmd.OutOrStdout(), ".", entries)
+			return printWorkspacePlan(cmd.OutOrStdout(), ".", data, opts, ws)
+		}
 
-		if err := scaf_fold.Generate(".", data); err != nil {
+		result, err := scaf_fold.GenerateWithOptions(".", data, opts)
+		if err != nil {
 			return fmt.Errorf("initialize project: %w", err)
 		}
 
-		fmt.Println("Project initialized in current directory")
-		fmt.Println()
-		printNextSteps(".", false)
+		fmt.Fprintln(cmd.OutOrStdout(), "Project initialized in current directory")
+		fmt.Fprintln(cmd.OutOrStdout())
+		printGenerateResult(cmd.OutOrStdout(), result)
+		if err := joinWorkspace(cmd.OutOrStdout(), ".", data, opts, ws); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		tidied, err := runHooks(cmd, ".", hooks, result)
+		if err != nil {
+			return err
+		}
+		printNextSteps(cmd.OutOrStdout(), ".", false, tidied)
 		return nil
 	},
 }
@@ -46,7 +107,7 @@ func initInit() {
 		"module",
 		"m",
 		"",
-		"Go module path (default: example.com/<directory-name>)",
+		"Go module path (default: the import path of the directory inside an enclosing Go module, otherwise example.com/<directory-name>)",
 	)
 	initCmd.Flags().StringVarP(
 		&initBinaryNameFlag,
@@ -59,7 +120,87 @@ func initInit() {
 		&initDBFlag,
 		"db",
 		"mysql,mongodb",
-		"Database engines: mysql, mongodb, or mysql,mongodb",
+		"Database engines: mysql, postgres or sqlite, optionally combined with mongodb (e.g. postgres,mongodb)",
+	)
+	initCmd.Flags().StringVar(
+		&initWithFlag,
+		"with",
+		"",
+		"Comma-separated optional components to enable: "+componentFlagUsage(),
+	)
+	initCmd.Flags().StringVar(
+		&initWithoutFlag,
+		"without",
+		"",
+		"Comma-separated optional components to disable",
+	)
+	initCmd.Flags().BoolVar(
+		&initDryRunFlag,
+		"dry-run",
+		false,
+		"Print the files that would be generated without writing them",
+	)
+	initCmd.Flags().StringVar(
+		&initTemplateDirFlag,
+		"template-dir",
+		"",
+		"Load templates from this directory instead of the built-in templates",
+	)
+	initCmd.Flags().StringArrayVar(
+		&initOverlayFlag,
+		"overlay",
+		nil,
+		"Template directory layered on top of the templates (repeatable, later wins)",
+	)
+
+	initCmd.Flags().StringVar(
+		&initConfigFlag,
+		"config",
+		"",
+		"YAML file with generator options (module, modulePrefix, binary, db, components, ...)",
+	)
+	initCmd.Flags().StringVar(
+		&initPresetFlag,
+		"preset",
+		"",
+		"Named preset from ~/.config/go-web-starter/presets.yaml; explicit flags override it",
+	)
+	initCmd.Flags().StringArrayVar(
+		&initSetFlag,
+		"set",
+		nil,
+		"Template variable key=value available as {{ .Extra.key }} (repeatable)",
+	)
+	initCmd.Flags().StringArrayVar(
+		&initSetFileFlag,
+		"set-file",
+		nil,
+		"Template variable key=path whose value is read from the file (repeatable)",
+	)
+	initCmd.Flags().StringVar(
+		&initHooksFlag,
+		"hooks",
+		strings.Join(scaf_fold.DefaultHooks(), ","),
+		"Comma-separated post-generation hooks to run (default: none): tidy (go mod tidy), fmt (gofmt of the generated Go files), git (git init and commit of the generated files)",
+	)
+	initCmd.Flags().BoolVar(
+		&initNoHooksFlag,
+		"no-hooks",
+		false,
+		"Skip all post-generation hooks",
+	)
+	initCmd.Flags().StringVar(
+		&initOnConflictFlag,
+		"on-conflict",
+		string(scaf_fold.ConflictFail),
+		"What to do with existing files that differ from the render: fail, skip, overwrite (keeps <file>.orig, never replacing an existing one) or prompt; "+
+			"other files already in the directory are left alone, so a non-empty directory is accepted even with fail",
+	)
+	initCmd.Flags().BoolVar(
+		&initNoModuleFlag,
+		"no-module",
+		false,
+		"Generate into the enclosing Go module: import paths under its module path and no separate go.mod",
 	)
 
 	rootCmd.AddCommand(initCmd)
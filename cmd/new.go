@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,9 +13,22 @@ import (
 )
 
 var (
-	moduleNameFlag string
-	binaryNameFlag string
-	dbFlag         string
+	moduleNameFlag  string
+	binaryNameFlag  string
+	dbFlag          string
+	dryRunFlag      bool
+	templateDirFlag string
+	overlayFlag     []string
+	withFlag        string
+	withoutFlag     string
+	configFlag      string
+	presetFlag      string
+	setFlag         []string
+	setFileFlag     []string
+	hooksFlag       string
+	noHooksFlag     bool
+	onConflictFlag  string
+	noModuleFlag    bool
 )
 
 var newCmd = &cobra.Command{
@@ -23,22 +37,69 @@ var newCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		outputDir := args[0]
-		data, err := buildTemplateData(
-			outputDir,
-			moduleNameFlag,
-			binaryNameFlag,
-			dbFlag,
+		var (
+			data scaf_fold.TemplateData
+			opts scaf_fold.Options
+			err  error
 		)
+		if shouldRunWizard(cmd) {
+			data, err = runWizard(cmd.InOrStdin(), cmd.OutOrStdout(), outputDir)
+		} else {
+			data, opts, err = resolveGenerator(cmd, outputDir, generatorFlags{
+				module:      moduleNameFlag,
+				binary:      binaryNameFlag,
+				db:          dbFlag,
+				with:        withFlag,
+				without:     withoutFlag,
+				templateDir: templateDirFlag,
+				overlays:    overlayFlag,
+				config:      configFlag,
+				preset:      presetFlag,
+				sets:        setFlag,
+				setFiles:    setFileFlag,
+				noModule:    noModuleFlag,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		if err := applyConflictFlag(cmd, onConflictFlag, &opts); err != nil {
+			return err
+		}
+		hooks, err := resolveHooks(cmd, hooksFlag, noHooksFlag)
+		if err != nil {
+			return err
+		}
+		ws, err := scaf_fold.FindWorkspace(outputDir)
 		if err != nil {
 			return err
 		}
 
-		if err := scaf_fold.Generate(outputDir, data); err != nil {
+		if dryRunFlag {
+			entries, err := scaf_fold.PlanWithOptions(data, opts)
+			if err != nil {
+				return fmt.Errorf("plan project: %w", err)
+			}
+			printPlan(cmd.OutOrStdout(), outputDir, entries)
+			return printWorkspacePlan(cmd.OutOrStdout(), outputDir, data, opts, ws)
+		}
+
+		result, err := scaf_fold.GenerateWithOptions(outputDir, data, opts)
+		if err != nil {
 			return fmt.Errorf("generate project: %w", err)
 		}
 
-		fmt.Printf("Project generated at %s\n\n", outputDir)
-		printNextSteps(outputDir, true)
+		fmt.Fprintf(cmd.OutOrStdout(), "Project generated at %s\n\n", outputDir)
+		printGenerateResult(cmd.OutOrStdout(), result)
+		if err := joinWorkspace(cmd.OutOrStdout(), outputDir, data, opts, ws); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		tidied, err := runHooks(cmd, outputDir, hooks, result)
+		if err != nil {
+			return err
+		}
+		printNextSteps(cmd.OutOrStdout(), outputDir, true, tidied)
 		return nil
 	},
 }
@@ -49,7 +110,7 @@ func initNew() {
 		"module",
 		"m",
 		"",
-		"Go module path (default: example.com/<directory-name>)",
+		"Go module path (default: the import path of the directory inside an enclosing Go module, otherwise example.com/<directory-name>)",
 	)
 	newCmd.Flags().StringVarP(
 		&binaryNameFlag,
@@ -62,7 +123,87 @@ func initNew() {
 		&dbFlag,
 		"db",
 		"mysql,mongodb",
-		"Database engines: mysql, mongodb, or mysql,mongodb",
+		"Database engines: mysql, postgres or sqlite, optionally combined with mongodb (e.g. postgres,mongodb)",
+	)
+	newCmd.Flags().StringVar(
+		&withFlag,
+		"with",
+		"",
+		"Comma-separated optional components to enable: "+componentFlagUsage(),
+	)
+	newCmd.Flags().StringVar(
+		&withoutFlag,
+		"without",
+		"",
+		"Comma-separated optional components to disable",
+	)
+	newCmd.Flags().BoolVar(
+		&dryRunFlag,
+		"dry-run",
+		false,
+		"Print the files that would be generated without writing them",
+	)
+	newCmd.Flags().StringVar(
+		&templateDirFlag,
+		"template-dir",
+		"",
+		"Load templates from this directory instead of the built-in templates",
+	)
+	newCmd.Flags().StringArrayVar(
+		&overlayFlag,
+		"overlay",
+		nil,
+		"Template directory layered on top of the templates (repeatable, later wins)",
+	)
+
+	newCmd.Flags().StringVar(
+		&configFlag,
+		"config",
+		"",
+		"YAML file with generator options (module, modulePrefix, binary, db, components, ...)",
+	)
+	newCmd.Flags().StringVar(
+		&presetFlag,
+		"preset",
+		"",
+		"Named preset from ~/.config/go-web-starter/presets.yaml; explicit flags override it",
+	)
+	newCmd.Flags().StringArrayVar(
+		&setFlag,
+		"set",
+		nil,
+		"Template variable key=value available as {{ .Extra.key }} (repeatable)",
+	)
+	newCmd.Flags().StringArrayVar(
+		&setFileFlag,
+		"set-file",
+		nil,
+		"Template variable key=path whose value is read from the file (repeatable)",
+	)
+	newCmd.Flags().StringVar(
+		&hooksFlag,
+		"hooks",
+		strings.Join(scaf_fold.DefaultHooks(), ","),
+		"Comma-separated post-generation hooks to run (default: none): tidy (go mod tidy), fmt (gofmt of the generated Go files), git (git init and commit of the generated files)",
+	)
+	newCmd.Flags().BoolVar(
+		&noHooksFlag,
+		"no-hooks",
+		false,
+		"Skip all post-generation hooks",
+	)
+	newCmd.Flags().StringVar(
+		&onConflictFlag,
+		"on-conflict",
+		string(scaf_fold.ConflictFail),
+		"What to do with existing files that differ from the render: fail, skip, overwrite (keeps <file>.orig, never replacing an existing one) or prompt; "+
+			"other files already in the directory are left alone, so a non-empty directory is accepted even with fail",
+	)
+	newCmd.Flags().BoolVar(
+		&noModuleFlag,
+		"no-module",
+		false,
+		"Generate into the enclosing Go module: import paths under its module path and no separate go.mod",
 	)
 
 	rootCmd.AddCommand(newCmd)
@@ -81,7 +222,7 @@ func buildTemplateData(
 
 	moduleName := strings.TrimSpace(moduleValue)
 	if moduleName == "" {
-		moduleName = defaultModuleName(projectName)
+		moduleName = defaultModuleName(outputDir, projectName)
 	}
 
 	binaryName := strings.TrimSpace(binaryValue)
@@ -89,7 +230,7 @@ func buildTemplateData(
 		binaryName = projectName
 	}
 
-	mysql, mongodb, err := scaf_fold.ParseDBFlag(dbValue)
+	dbs, err := scaf_fold.ParseDBFlag(dbValue)
 	if err != nil {
 		return scaf_fold.TemplateData{}, err
 	}
@@ -98,11 +239,21 @@ func buildTemplateData(
 		ModuleName:  moduleName,
 		BinaryName:  binaryName,
 		ProjectName: projectName,
-		MySQL:       mysql,
-		MongoDB:     mongodb,
+		MySQL:       dbs.MySQL,
+		Postgres:    dbs.Postgres,
+		SQLite:      dbs.SQLite,
+		MongoDB:     dbs.MongoDB,
 	}, nil
 }
 
+func componentFlagUsage() string {
+	names := make([]string, 0)
+	for _, component := range scaf_fold.Components() {
+		names = append(names, component.Name)
+	}
+	return strings.Join(names, ",")
+}
+
 func inferProjectName(outputDir string) (string, error) {
 	cleanedOutputDir := filepath.Clean(strings.TrimSpace(outputDir))
 	if cleanedOutputDir == "." {
@@ -138,16 +289,41 @@ func sanitizeDerivedProjectName(projectName, source string) (string, error) {
 	return name, nil
 }
 
-func defaultModuleName(projectName string) string {
+// defaultModuleName returns the import path of outputDir inside the
+// enclosing module, or example.com/<projectName> outside of any module.
+func defaultModuleName(outputDir, projectName string) string {
+	if ws, err := scaf_fold.FindWorkspace(outputDir); err == nil && ws.ModFile != "" {
+		if importPath, _, err := ws.ImportPath(outputDir); err == nil {
+			return importPath
+		}
+	}
 	return fmt.Sprintf("example.com/%s", projectName)
 }
 
-func printNextSteps(outputDir string, includeCD bool) {
-	fmt.Println("Next steps:")
+func printNextSteps(w io.Writer, outputDir string, includeCD, tidied bool) {
+	fmt.Fprintln(w, "Next steps:")
 	if includeCD {
-		fmt.Printf("  cd %s\n", outputDir)
+		fmt.Fprintf(w, "  cd %s\n", outputDir)
+	}
+	if !tidied {
+		fmt.Fprintln(w, "  go mod tidy")
+	}
+	fmt.Fprintln(w, "  # edit config/config.yml")
+	fmt.Fprintln(w, "  go run ./app/main.go http")
+}
+
+func printPlan(w io.Writer, outputDir string, entries []scaf_fold.PlannedEntry) {
+	var dirs, files, size int
+	fmt.Fprintf(w, "Dry run: nothing written to %s\n\n", outputDir)
+	for _, entry := range entries {
+		if entry.IsDir {
+			dirs++
+			fmt.Fprintf(w, "  dir   %s/\n", entry.Path)
+			continue
+		}
+		files++
+		size += entry.Size
+		fmt.Fprintf(w, "  file  %s (%d bytes)\n", entry.Path, entry.Size)
 	}
-	fmt.Println("  go mod tidy")
-	fmt.Println("  # edit config/config.yml")
-	fmt.Println("  go run ./app/main.go http")
+	fmt.Fprintf(w, "\n%d directories, %d files, %d bytes\n", dirs, files, size)
 }
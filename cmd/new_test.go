@@ -109,35 +109,45 @@ func TestBuildTemplateDataWithCustomValues(t *testing.T) {
 
 func TestParseDBFlagBoundaries(t *testing.T) {
 	tests := []struct {
-		name      string
-		val       string
-		wantMySQL bool
-		wantMongo bool
-		wantErr   bool
+		name    string
+		val     string
+		want    scaf_fold.Databases
+		wantErr bool
 	}{
 		{
-			name:      "default both",
-			val:       "mysql,mongodb",
-			wantMySQL: true,
-			wantMongo: true,
+			name: "default both",
+			val:  "mysql,mongodb",
+			want: scaf_fold.Databases{MySQL: true, MongoDB: true},
+		},
+		{
+			name: "case insensitive and spaces",
+			val:  " MySQL , MONGODB ",
+			want: scaf_fold.Databases{MySQL: true, MongoDB: true},
+		},
+		{
+			name: "ignore empty token",
+			val:  "mysql,,mongodb",
+			want: scaf_fold.Databases{MySQL: true, MongoDB: true},
+		},
+		{
+			name: "single mysql with empty token",
+			val:  ", mysql, ",
+			want: scaf_fold.Databases{MySQL: true},
 		},
 		{
-			name:      "case insensitive and spaces",
-			val:       " MySQL , MONGODB ",
-			wantMySQL: true,
-			wantMongo: true,
+			name: "postgres",
+			val:  "postgres",
+			want: scaf_fold.Databases{Postgres: true},
 		},
 		{
-			name:      "ignore empty token",
-			val:       "mysql,,mongodb",
-			wantMySQL: true,
-			wantMongo: true,
+			name: "postgres and mongodb",
+			val:  "Postgres,mongodb",
+			want: scaf_fold.Databases{Postgres: true, MongoDB: true},
 		},
 		{
-			name:      "single mysql with empty token",
-			val:       ", mysql, ",
-			wantMySQL: true,
-			wantMongo: false,
+			name: "sqlite",
+			val:  "sqlite",
+			want: scaf_fold.Databases{SQLite: true},
 		},
 		{
 			name:    "empty after trim",
@@ -146,14 +156,24 @@ func TestParseDBFlagBoundaries(t *testing.T) {
 		},
 		{
 			name:    "invalid token",
+			val:     "mysql,oracle",
+			wantErr: true,
+		},
+		{
+			name:    "two sql engines",
 			val:     "mysql,postgres",
 			wantErr: true,
 		},
+		{
+			name:    "sqlite with another sql engine",
+			val:     "sqlite,postgres",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mysql, mongo, err := scaf_fold.ParseDBFlag(tt.val)
+			got, err := scaf_fold.ParseDBFlag(tt.val)
 			if tt.wantErr {
 				if err == nil {
 					t.Fatalf("ParseDBFlag(%q) expected error, got nil", tt.val)
@@ -163,15 +183,8 @@ func TestParseDBFlagBoundaries(t *testing.T) {
 			if err != nil {
 				t.Fatalf("ParseDBFlag(%q) error = %v", tt.val, err)
 			}
-			if mysql != tt.wantMySQL || mongo != tt.wantMongo {
-				t.Fatalf(
-					"ParseDBFlag(%q) = (mysql=%v,mongo=%v), want (mysql=%v,mongo=%v)",
-					tt.val,
-					mysql,
-					mongo,
-					tt.wantMySQL,
-					tt.wantMongo,
-				)
+			if got != tt.want {
+				t.Fatalf("ParseDBFlag(%q) = %+v, want %+v", tt.val, got, tt.want)
 			}
 		})
 	}
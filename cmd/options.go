@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SisyphusSQ/go-web-starter/internal/scaf_fold"
+)
+
+// generatorFlags are the values of the generator flags shared by new and
+// init.
+type generatorFlags struct {
+	module      string
+	binary      string
+	db          string
+	with        string
+	without     string
+	templateDir string
+	overlays    []string
+	config      string
+	preset      string
+	sets        []string
+	setFiles    []string
+	noModule    bool
+}
+
+// resolveGenerator merges the preset, the config file and the flags given
+// explicitly on cmd, in increasing precedence, into the template data and
+// template options of the project generated into outputDir.
+func resolveGenerator(
+	cmd *cobra.Command,
+	outputDir string,
+	flags generatorFlags,
+) (scaf_fold.TemplateData, scaf_fold.Options, error) {
+	var cfg scaf_fold.GeneratorConfig
+	if flags.preset != "" {
+		presetsPath, err := scaf_fold.DefaultPresetsPath()
+		if err != nil {
+			return scaf_fold.TemplateData{}, scaf_fold.Options{}, err
+		}
+		if cfg, err = scaf_fold.LoadPreset(presetsPath, flags.preset); err != nil {
+			return scaf_fold.TemplateData{}, scaf_fold.Options{}, err
+		}
+	}
+	if flags.config != "" {
+		fileCfg, err := scaf_fold.LoadConfig(flags.config)
+		if err != nil {
+			return scaf_fold.TemplateData{}, scaf_fold.Options{}, err
+		}
+		cfg = cfg.Merge(fileCfg)
+	}
+
+	var (
+		explicit scaf_fold.GeneratorConfig
+		err      error
+	)
+	changed := cmd.Flags().Changed
+	if changed("module") {
+		explicit.Module = flags.module
+	}
+	if changed("binary") {
+		explicit.Binary = flags.binary
+	}
+	if changed("db") {
+		explicit.DB = flags.db
+	}
+	if changed("template-dir") {
+		explicit.TemplateDir = flags.templateDir
+	}
+	if changed("overlay") {
+		explicit.Overlays = flags.overlays
+	}
+	if explicit.Extra, err = scaf_fold.ParseSetFlags(flags.sets, flags.setFiles); err != nil {
+		return scaf_fold.TemplateData{}, scaf_fold.Options{}, err
+	}
+	cfg = cfg.Merge(explicit)
+
+	projectName, err := inferProjectName(outputDir)
+	if err != nil {
+		return scaf_fold.TemplateData{}, scaf_fold.Options{}, err
+	}
+	dbValue := cfg.DB
+	if dbValue == "" {
+		dbValue = flags.db
+	}
+	moduleValue := cfg.ModuleFor(projectName)
+	var moduleSubdir string
+	if flags.noModule {
+		if changed("module") {
+			return scaf_fold.TemplateData{}, scaf_fold.Options{}, fmt.Errorf(
+				"--module cannot be combined with --no-module: the import path is derived from the enclosing module",
+			)
+		}
+		ws, err := scaf_fold.FindWorkspace(outputDir)
+		if err != nil {
+			return scaf_fold.TemplateData{}, scaf_fold.Options{}, err
+		}
+		configured := moduleValue
+		if moduleValue, moduleSubdir, err = ws.ImportPath(outputDir); err != nil {
+			return scaf_fold.TemplateData{}, scaf_fold.Options{}, fmt.Errorf("--no-module: %w", err)
+		}
+		if moduleSubdir == "." {
+			return scaf_fold.TemplateData{}, scaf_fold.Options{}, fmt.Errorf(
+				"--no-module: %s is the root of module %s; generate into a directory below it",
+				outputDir,
+				ws.ModulePath,
+			)
+		}
+		if configured != "" && configured != moduleValue {
+			fmt.Fprintf(
+				cmd.ErrOrStderr(),
+				"Warning: --no-module ignores module %s from the config file or preset; the import path is %s\n",
+				configured,
+				moduleValue,
+			)
+		}
+	}
+	data, err := buildTemplateData(outputDir, moduleValue, cfg.Binary, dbValue)
+	if err != nil {
+		return scaf_fold.TemplateData{}, scaf_fold.Options{}, err
+	}
+	data.GoVersion = cfg.GoVersion
+	data.Extra = cfg.Extra
+	data.NoModule = flags.noModule
+	data.ModuleSubdir = moduleSubdir
+
+	components := cfg.Components
+	if components == nil {
+		components = scaf_fold.DefaultComponents()
+	}
+	data.Components, err = scaf_fold.ApplyComponentFlags(components, flags.with, flags.without)
+	if err != nil {
+		return scaf_fold.TemplateData{}, scaf_fold.Options{}, err
+	}
+
+	if err := data.Validate(); err != nil {
+		return scaf_fold.TemplateData{}, scaf_fold.Options{}, fmt.Errorf("invalid template data: %w", err)
+	}
+	return data, scaf_fold.Options{TemplateDir: cfg.TemplateDir, Overlays: cfg.Overlays}, nil
+}
@@ -25,6 +25,10 @@ func initAll() {
 	initVersion()
 	initInit()
 	initNew()
+	initAdd()
+	initUpgrade()
+	initDiff()
+	initTemplates()
 }
 
 func ensureInitialized() {
@@ -6,6 +6,11 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/SisyphusSQ/go-web-starter/internal/scaf_fold"
 )
 
 func TestRootExecuteNewRequiresOutputDir(t *testing.T) {
@@ -20,7 +25,7 @@ func TestRootExecuteNewRequiresOutputDir(t *testing.T) {
 
 func TestRootExecuteNewRejectsInvalidDBFlag(t *testing.T) {
 	outDir := filepath.Join(t.TempDir(), "demo")
-	err := executeRootForTest(nil, "new", outDir, "--db", "postgres")
+	err := executeRootForTest(nil, "new", outDir, "--db", "oracle")
 	if err == nil {
 		t.Fatal("expected invalid db error, got nil")
 	}
@@ -64,15 +69,125 @@ func TestRootExecuteNewHelpContainsDBFlag(t *testing.T) {
 	if err := executeRootForTest(&output, "new", "--help"); err != nil {
 		t.Fatalf("execute new --help failed: %v", err)
 	}
-	if !strings.Contains(output.String(), "Database engines: mysql, mongodb, or mysql,mongodb") {
+	if !strings.Contains(output.String(), "Database engines: mysql, postgres or sqlite, optionally combined with mongodb") {
 		t.Fatalf("help output missing db flag description:\n%s", output.String())
 	}
 }
 
+func TestRootExecuteNewDryRunWritesNothing(t *testing.T) {
+	outDir := filepath.Join(t.TempDir(), "demo-dry-run")
+	var output bytes.Buffer
+	if err := executeRootForTest(&output, "new", outDir, "--db", "mongodb", "--dry-run"); err != nil {
+		t.Fatalf("execute new --dry-run failed: %v", err)
+	}
+
+	if _, err := os.Stat(outDir); !os.IsNotExist(err) {
+		t.Fatalf("dry run should not create %s, got err=%v", outDir, err)
+	}
+
+	text := output.String()
+	for _, want := range []string{
+		"Dry run: nothing written to " + outDir,
+		"file  internal/lib/mongodb/mongodb.go (",
+		"dir   internal/lib/mongodb/",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("dry run output missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "internal/lib/gorm") {
+		t.Fatalf("mongodb dry run should not list gorm files:\n%s", text)
+	}
+}
+
+func TestRootExecuteNewWithTemplateDir(t *testing.T) {
+	templateDir := t.TempDir()
+	if err := os.WriteFile(
+		filepath.Join(templateDir, "go.mod.tmpl"),
+		[]byte("module {{ .ModuleName }}\n"),
+		0o644,
+	); err != nil {
+		t.Fatalf("write go.mod.tmpl: %v", err)
+	}
+
+	outDir := filepath.Join(t.TempDir(), "demo-template-dir")
+	if err := executeRootForTest(nil, "new", outDir, "--template-dir", templateDir); err != nil {
+		t.Fatalf("execute new --template-dir failed: %v", err)
+	}
+
+	goMod, err := os.ReadFile(filepath.Join(outDir, "go.mod"))
+	if err != nil {
+		t.Fatalf("read generated go.mod: %v", err)
+	}
+	if string(goMod) != "module example.com/demo-template-dir\n" {
+		t.Fatalf("go.mod = %q", goMod)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "app")); !os.IsNotExist(err) {
+		t.Fatalf("embedded templates should not be rendered, got err=%v", err)
+	}
+}
+
+func TestRootExecuteNewWithOverlays(t *testing.T) {
+	firstOverlay := t.TempDir()
+	secondOverlay := t.TempDir()
+	writeFileForTest(t, filepath.Join(firstOverlay, "README.md.tmpl"), "first {{ .ProjectName }}\n")
+	writeFileForTest(t, filepath.Join(secondOverlay, "README.md.tmpl"), "second {{ .ProjectName }}\n")
+	writeFileForTest(t, filepath.Join(secondOverlay, "Dockerfile.delete"), "")
+
+	outDir := filepath.Join(t.TempDir(), "demo-overlay")
+	if err := executeRootForTest(
+		nil,
+		"new", outDir,
+		"--overlay", firstOverlay,
+		"--overlay", secondOverlay,
+	); err != nil {
+		t.Fatalf("execute new --overlay failed: %v", err)
+	}
+
+	readme, err := os.ReadFile(filepath.Join(outDir, "README.md"))
+	if err != nil {
+		t.Fatalf("read generated README.md: %v", err)
+	}
+	if string(readme) != "second demo-overlay\n" {
+		t.Fatalf("README.md = %q, want the last overlay to win", readme)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "Dockerfile")); !os.IsNotExist(err) {
+		t.Fatalf("Dockerfile should be deleted by overlay marker, got err=%v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "app", "main.go")); err != nil {
+		t.Fatalf("embedded templates should still be rendered: %v", err)
+	}
+}
+
+func writeFileForTest(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir for %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func readFileForTest(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(content)
+}
+
 func executeRootForTest(output *bytes.Buffer, args ...string) error {
+	return executeRootWithInputForTest(output, "", args...)
+}
+
+func executeRootWithInputForTest(output *bytes.Buffer, input string, args ...string) error {
 	ensureInitialized()
 	resetCLIFlagStateForTest()
 
+	rootCmd.SetIn(strings.NewReader(input))
+
 	rootCmd.SilenceErrors = true
 	rootCmd.SilenceUsage = true
 	if output == nil {
@@ -91,7 +206,483 @@ func resetCLIFlagStateForTest() {
 	moduleNameFlag = ""
 	binaryNameFlag = ""
 	dbFlag = "mysql,mongodb"
+	dryRunFlag = false
+	templateDirFlag = ""
+	overlayFlag = nil
+	withFlag = ""
+	withoutFlag = ""
+	configFlag = ""
+	presetFlag = ""
+	setFlag = nil
+	setFileFlag = nil
+	hooksFlag = strings.Join(scaf_fold.DefaultHooks(), ",")
+	noHooksFlag = false
+	onConflictFlag = "fail"
+	noModuleFlag = false
 	initModuleNameFlag = ""
 	initBinaryNameFlag = ""
 	initDBFlag = "mysql,mongodb"
+	initDryRunFlag = false
+	initTemplateDirFlag = ""
+	initOverlayFlag = nil
+	initWithFlag = ""
+	initWithoutFlag = ""
+	initConfigFlag = ""
+	initPresetFlag = ""
+	initSetFlag = nil
+	initSetFileFlag = nil
+	initHooksFlag = strings.Join(scaf_fold.DefaultHooks(), ",")
+	initNoHooksFlag = false
+	initOnConflictFlag = "fail"
+	initNoModuleFlag = false
+	addFieldsFlag = ""
+	addDBFlag = ""
+	addDirFlag = "."
+	upgradeDirFlag = "."
+	upgradeTemplateDirFlag = ""
+	upgradeOverlayFlag = nil
+	diffDirFlag = "."
+	diffTemplateDirFlag = ""
+	diffOverlayFlag = nil
+	diffNameOnlyFlag = false
+	lintOverlayFlag = nil
+	hookRunner = &recordingRunnerForTest{}
+
+	resetCommandFlagsForTest(rootCmd)
+}
+
+// resetCommandFlagsForTest clears the help flag and the Changed marks that
+// cobra uses to check required flags, on every command in the tree.
+func resetCommandFlagsForTest(parent *cobra.Command) {
+	for _, c := range parent.Commands() {
+		if help := c.Flags().Lookup("help"); help != nil {
+			_ = help.Value.Set("false")
+		}
+		c.Flags().VisitAll(func(f *pflag.Flag) {
+			f.Changed = false
+		})
+		resetCommandFlagsForTest(c)
+	}
+}
+
+func TestRootExecuteAddResource(t *testing.T) {
+	outDir := filepath.Join(t.TempDir(), "demo-add")
+	if err := executeRootForTest(nil, "new", outDir, "--db", "postgres"); err != nil {
+		t.Fatalf("execute new failed: %v", err)
+	}
+
+	var output bytes.Buffer
+	if err := executeRootForTest(
+		&output,
+		"add", "resource", "order",
+		"--fields", "name:string,amount:int64",
+		"--dir", outDir,
+	); err != nil {
+		t.Fatalf("execute add resource failed: %v", err)
+	}
+
+	text := output.String()
+	for _, want := range []string{
+		"Resource order added (postgres)",
+		"create  internal/service/example_srv/order_service.go",
+		"update  internal/controller/module.go",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("add resource output missing %q:\n%s", want, text)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "docs", "schema", "orders.sql")); err != nil {
+		t.Fatalf("add resource should write the DDL: %v", err)
+	}
+}
+
+func TestRootExecuteAddResourceRequiresFields(t *testing.T) {
+	err := executeRootForTest(nil, "add", "resource", "order", "--dir", t.TempDir())
+	if err == nil {
+		t.Fatal("expected missing fields error, got nil")
+	}
+	if !strings.Contains(err.Error(), `required flag(s) "fields" not set`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRootExecuteUpgrade(t *testing.T) {
+	outDir := filepath.Join(t.TempDir(), "demo-upgrade")
+	if err := executeRootForTest(nil, "new", outDir, "--db", "mysql"); err != nil {
+		t.Fatalf("execute new failed: %v", err)
+	}
+
+	var output bytes.Buffer
+	if err := executeRootForTest(&output, "upgrade", "--dir", outDir); err != nil {
+		t.Fatalf("execute upgrade failed: %v", err)
+	}
+	if !strings.Contains(output.String(), "project is up to date") {
+		t.Fatalf("upgrade of a fresh project should change nothing:\n%s", output.String())
+	}
+
+	overlay := t.TempDir()
+	writeFileForTest(t, filepath.Join(overlay, "README.md.tmpl"), "# {{ .ProjectName }}\n")
+	writeFileForTest(t, filepath.Join(outDir, "README.md"), "# local readme\n")
+	err := executeRootForTest(&output, "upgrade", "--dir", outDir, "--overlay", overlay)
+	if err == nil || !strings.Contains(err.Error(), "upgrade left 0 conflicted and 1 rejected files to resolve") {
+		t.Fatalf("execute upgrade error = %v", err)
+	}
+	if !strings.Contains(output.String(), "reject    README.md") {
+		t.Fatalf("upgrade output missing rejected README.md:\n%s", output.String())
+	}
+}
+
+func TestRootExecuteDiff(t *testing.T) {
+	outDir := filepath.Join(t.TempDir(), "demo-diff")
+	if err := executeRootForTest(nil, "new", outDir, "--db", "mongodb"); err != nil {
+		t.Fatalf("execute new failed: %v", err)
+	}
+
+	var output bytes.Buffer
+	if err := executeRootForTest(&output, "diff", "--dir", outDir); err != nil {
+		t.Fatalf("execute diff failed: %v", err)
+	}
+	if !strings.Contains(output.String(), "No differences") {
+		t.Fatalf("diff of a fresh project should be empty:\n%s", output.String())
+	}
+
+	writeFileForTest(t, filepath.Join(outDir, "README.md"), "# local readme\n")
+	if err := os.Remove(filepath.Join(outDir, "Dockerfile")); err != nil {
+		t.Fatalf("remove Dockerfile: %v", err)
+	}
+	if err := executeRootForTest(&output, "diff", "--dir", outDir, "--name-only"); err != nil {
+		t.Fatalf("execute diff --name-only failed: %v", err)
+	}
+	want := "## Modified locally (1)\n  README.md\n\n## Missing (1)\n  Dockerfile\n"
+	if !strings.Contains(output.String(), want) {
+		t.Fatalf("diff --name-only output missing %q:\n%s", want, output.String())
+	}
+}
+
+func TestRootExecuteNewWithPresetAndFlagOverrides(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	writeFileForTest(t, filepath.Join(configHome, "go-web-starter", "presets.yaml"), "presets:\n"+
+		"  internal-api:\n"+
+		"    modulePrefix: git.acme.io/services\n"+
+		"    db: postgres\n"+
+		"    components: [jwt, cron]\n")
+
+	outDir := filepath.Join(t.TempDir(), "orders")
+	if err := executeRootForTest(
+		nil,
+		"new", outDir,
+		"--preset", "internal-api",
+		"--db", "sqlite",
+		"--without", "cron",
+	); err != nil {
+		t.Fatalf("execute new --preset failed: %v", err)
+	}
+
+	lockfile, err := os.ReadFile(filepath.Join(outDir, ".go-web-starter.json"))
+	if err != nil {
+		t.Fatalf("read lockfile: %v", err)
+	}
+	for _, want := range []string{
+		`"moduleName": "git.acme.io/services/orders"`,
+		`"postgres": false`,
+		`"sqlite": true`,
+		"\"components\": [\n      \"jwt\"\n    ]",
+	} {
+		if !strings.Contains(string(lockfile), want) {
+			t.Fatalf("lockfile missing %q:\n%s", want, lockfile)
+		}
+	}
+}
+
+func TestRootExecuteInitWithConfigFile(t *testing.T) {
+	baseDir := t.TempDir()
+	configPath := filepath.Join(baseDir, "starter.yaml")
+	writeFileForTest(t, configPath, "module: github.com/acme/from-config\nbinary: from-config\ndb: mongodb\ncomponents: []\n")
+
+	projectDir := filepath.Join(baseDir, "project")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatalf("mkdir project dir: %v", err)
+	}
+	originalWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("get current directory: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(originalWD)
+	})
+	if err := os.Chdir(projectDir); err != nil {
+		t.Fatalf("chdir project dir: %v", err)
+	}
+
+	if err := executeRootForTest(nil, "init", "--config", configPath, "--binary", "override"); err != nil {
+		t.Fatalf("execute init --config failed: %v", err)
+	}
+
+	lockfile, err := os.ReadFile(filepath.Join(projectDir, ".go-web-starter.json"))
+	if err != nil {
+		t.Fatalf("read lockfile: %v", err)
+	}
+	for _, want := range []string{
+		`"moduleName": "github.com/acme/from-config"`,
+		`"binaryName": "override"`,
+		`"mysql": false`,
+		`"components": []`,
+	} {
+		if !strings.Contains(string(lockfile), want) {
+			t.Fatalf("lockfile missing %q:\n%s", want, lockfile)
+		}
+	}
+}
+
+func TestRootExecuteNewValidatesMergedConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	configPath := filepath.Join(t.TempDir(), "starter.yaml")
+	writeFileForTest(t, configPath, "goVersion: latest\n")
+
+	err := executeRootForTest(nil, "new", filepath.Join(t.TempDir(), "demo"), "--config", configPath)
+	if err == nil || !strings.Contains(err.Error(), "invalid template data") {
+		t.Fatalf("execute new --config error = %v, want validation error", err)
+	}
+
+	err = executeRootForTest(nil, "new", filepath.Join(t.TempDir(), "demo"), "--preset", "missing")
+	if err == nil || !strings.Contains(err.Error(), "presets.yaml") {
+		t.Fatalf("execute new --preset error = %v, want missing presets file error", err)
+	}
+}
+
+func TestRootExecuteNewWithSetVariables(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	baseDir := t.TempDir()
+	overlayDir := filepath.Join(baseDir, "overlay")
+	writeFileForTest(t, filepath.Join(overlayDir, "OWNERS.tmpl"), "{{ .Extra.team }} {{ .Extra.region }}\n{{ .Extra.header }}\n")
+	writeFileForTest(t, filepath.Join(baseDir, "header.txt"), "// Copyright Acme\n")
+	configPath := filepath.Join(baseDir, "starter.yaml")
+	writeFileForTest(t, configPath, "extra:\n  team: core\n  region: eu\n")
+
+	outDir := filepath.Join(baseDir, "orders")
+	if err := executeRootForTest(
+		nil,
+		"new", outDir,
+		"--db", "sqlite",
+		"--overlay", overlayDir,
+		"--config", configPath,
+		"--set", "team=payments",
+		"--set-file", "header="+filepath.Join(baseDir, "header.txt"),
+	); err != nil {
+		t.Fatalf("execute new --set failed: %v", err)
+	}
+	owners, err := os.ReadFile(filepath.Join(outDir, "OWNERS"))
+	if err != nil {
+		t.Fatalf("read OWNERS: %v", err)
+	}
+	if string(owners) != "payments eu\n// Copyright Acme\n" {
+		t.Fatalf("OWNERS = %q", owners)
+	}
+
+	err = executeRootForTest(nil, "new", filepath.Join(baseDir, "missing"), "--overlay", overlayDir)
+	if err == nil || !strings.Contains(err.Error(), `OWNERS.tmpl: template variable "team" is not set`) {
+		t.Fatalf("execute new without --set error = %v, want missing variable error", err)
+	}
+}
+
+func TestRootExecuteTemplatesLint(t *testing.T) {
+	templateDir := t.TempDir()
+	writeFileForTest(t, filepath.Join(templateDir, "go.mod.tmpl"), "module {{ .ModuleName }}\n")
+	writeFileForTest(t, filepath.Join(templateDir, "main.go.tmpl"), "package main\n\nfunc main() {}\n")
+
+	var output bytes.Buffer
+	if err := executeRootForTest(&output, "templates", "lint", templateDir); err != nil {
+		t.Fatalf("templates lint error = %v\n%s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Checked 2 files across 224 combinations: 0 problem(s)") {
+		t.Fatalf("unexpected output:\n%s", output.String())
+	}
+
+	overlayDir := t.TempDir()
+	writeFileForTest(t, filepath.Join(overlayDir, "main.go.tmpl"), "package main\n\nfunc main() { {{ .Missing }} }\n")
+
+	err := executeRootForTest(&output, "templates", "lint", templateDir, "--overlay", overlayDir)
+	if err == nil || !strings.Contains(err.Error(), "template lint found 1 problem(s)") {
+		t.Fatalf("templates lint error = %v, want one problem", err)
+	}
+	got := output.String()
+	if !strings.Contains(got, "main.go.tmpl (db=mysql components=none):") ||
+		!strings.Contains(got, "can't evaluate field Missing") {
+		t.Fatalf("unexpected output:\n%s", got)
+	}
+}
+
+func TestRootExecuteNewOnConflict(t *testing.T) {
+	newProjectDir := func(t *testing.T) string {
+		projectDir := filepath.Join(t.TempDir(), "hosted-web")
+		writeFileForTest(t, filepath.Join(projectDir, "README.md"), "# hosted-web\n\nCreated by the Git host.\n")
+		return projectDir
+	}
+
+	var output bytes.Buffer
+	projectDir := newProjectDir(t)
+	err := executeRootForTest(&output, "new", projectDir, "--db", "sqlite", "--no-hooks")
+	if err == nil || !strings.Contains(err.Error(), "already exist") || !strings.Contains(err.Error(), "README.md") {
+		t.Fatalf("new into directory with README.md error = %v, want conflict error", err)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "go.mod")); !os.IsNotExist(err) {
+		t.Fatalf("go.mod written despite conflict, stat err=%v", err)
+	}
+
+	// fail only refuses differing files with a generated name.
+	unrelatedDir := filepath.Join(t.TempDir(), "notes-web")
+	writeFileForTest(t, filepath.Join(unrelatedDir, "NOTES.txt"), "mine\n")
+	if err := executeRootForTest(&output, "new", unrelatedDir, "--db", "sqlite", "--no-hooks"); err != nil {
+		t.Fatalf("new into directory with unrelated files error = %v", err)
+	}
+	if got := readFileForTest(t, filepath.Join(unrelatedDir, "NOTES.txt")); got != "mine\n" {
+		t.Fatalf("NOTES.txt changed to %q", got)
+	}
+
+	projectDir = newProjectDir(t)
+	if err := executeRootForTest(&output, "new", projectDir, "--db", "sqlite", "--no-hooks", "--on-conflict", "skip"); err != nil {
+		t.Fatalf("new --on-conflict=skip error = %v", err)
+	}
+	if !strings.Contains(output.String(), "  skip       README.md\n") ||
+		!strings.Contains(output.String(), "  create     go.mod\n") {
+		t.Fatalf("unexpected output:\n%s", output.String())
+	}
+	if got := readFileForTest(t, filepath.Join(projectDir, "README.md")); !strings.Contains(got, "Created by the Git host.") {
+		t.Fatalf("README.md replaced with skip: %q", got)
+	}
+
+	projectDir = newProjectDir(t)
+	err = executeRootWithInputForTest(&output, "maybe\ny\n", "new", projectDir, "--db", "sqlite", "--no-hooks", "--on-conflict", "prompt")
+	if err != nil {
+		t.Fatalf("new --on-conflict=prompt error = %v", err)
+	}
+	got := output.String()
+	if strings.Count(got, "README.md already exists with different content. Overwrite? [y/N]: ") != 2 ||
+		!strings.Contains(got, "please answer y or n") ||
+		!strings.Contains(got, "  overwrite  README.md (previous content kept in README.md.orig)\n") {
+		t.Fatalf("unexpected output:\n%s", got)
+	}
+	if backup := readFileForTest(t, filepath.Join(projectDir, "README.md.orig")); !strings.Contains(backup, "Created by the Git host.") {
+		t.Fatalf("README.md.orig = %q", backup)
+	}
+
+	if err := executeRootForTest(nil, "new", newProjectDir(t), "--on-conflict", "merge"); err == nil ||
+		!strings.Contains(err.Error(), "unsupported conflict strategy") {
+		t.Fatalf("new --on-conflict=merge error = %v", err)
+	}
+}
+
+func TestRootExecuteNewInsideMonorepo(t *testing.T) {
+	t.Setenv("GOWORK", "")
+	root := t.TempDir()
+	writeFileForTest(t, filepath.Join(root, "go.mod"), "module github.com/acme/mono\n\ngo 1.22\n")
+	writeFileForTest(t, filepath.Join(root, "go.work"), "go 1.22\n\nuse .\n")
+
+	var output bytes.Buffer
+	billingDir := filepath.Join(root, "services", "billing")
+	if err := executeRootForTest(&output, "new", billingDir, "--db", "sqlite", "--no-hooks"); err != nil {
+		t.Fatalf("new in workspace error = %v\n%s", err, output.String())
+	}
+	if got := readFileForTest(t, filepath.Join(billingDir, "go.mod")); !strings.HasPrefix(got, "module github.com/acme/mono/services/billing\n") {
+		t.Fatalf("go.mod does not default to the path inside the enclosing module:\n%s", got)
+	}
+	work := readFileForTest(t, filepath.Join(root, "go.work"))
+	if !strings.Contains(work, "\t./services/billing\n") {
+		t.Fatalf("go.work does not use the new module:\n%s", work)
+	}
+	if !strings.Contains(output.String(), "Added "+billingDir+" to workspace "+filepath.Join(root, "go.work")) {
+		t.Fatalf("unexpected output:\n%s", output.String())
+	}
+
+	ledgerDir := filepath.Join(root, "services", "ledger")
+	if err := executeRootForTest(&output, "new", ledgerDir, "--db", "sqlite", "--no-hooks", "--no-module"); err != nil {
+		t.Fatalf("new --no-module error = %v\n%s", err, output.String())
+	}
+	if _, err := os.Stat(filepath.Join(ledgerDir, "go.mod")); !os.IsNotExist(err) {
+		t.Fatalf("go.mod generated with --no-module, stat err=%v", err)
+	}
+	lock, err := scaf_fold.ReadLockfile(ledgerDir)
+	if err != nil {
+		t.Fatalf("ReadLockfile() error = %v", err)
+	}
+	if lock.Data.ModuleName != "github.com/acme/mono/services/ledger" || !lock.Data.NoModule ||
+		lock.Data.ModuleSubdir != "services/ledger" {
+		t.Fatalf("lockfile data = %+v", lock.Data)
+	}
+	if goMod := readFileForTest(t, filepath.Join(root, "go.mod")); !strings.Contains(goMod, "\tgorm.io/gorm ") {
+		t.Fatalf("enclosing go.mod misses the project requirements:\n%s", goMod)
+	}
+	if work := readFileForTest(t, filepath.Join(root, "go.work")); strings.Contains(work, "ledger") {
+		t.Fatalf("go.work uses the --no-module project:\n%s", work)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "starter.yaml")
+	writeFileForTest(t, configPath, "modulePrefix: github.com/acme\n")
+	paymentsDir := filepath.Join(root, "services", "payments")
+	if err := executeRootForTest(
+		&output,
+		"new", paymentsDir,
+		"--db", "sqlite", "--no-hooks", "--no-module", "--config", configPath,
+	); err != nil {
+		t.Fatalf("new --no-module --config error = %v\n%s", err, output.String())
+	}
+	want := "Warning: --no-module ignores module github.com/acme/payments from the config file or preset; " +
+		"the import path is github.com/acme/mono/services/payments\n"
+	if !strings.Contains(output.String(), want) {
+		t.Fatalf("new --no-module --config output misses the warning:\n%s", output.String())
+	}
+
+	err = executeRootForTest(nil, "new", filepath.Join(root, "services", "audit"), "--no-module", "--module", "example.com/audit")
+	if err == nil || !strings.Contains(err.Error(), "--module cannot be combined with --no-module") {
+		t.Fatalf("new --no-module --module error = %v", err)
+	}
+	err = executeRootForTest(nil, "new", filepath.Join(t.TempDir(), "standalone"), "--no-module")
+	if err == nil || !strings.Contains(err.Error(), "--no-module: no go.mod found") {
+		t.Fatalf("new --no-module outside of a module error = %v", err)
+	}
+}
+
+func TestRootExecuteNewDryRunInsideMonorepo(t *testing.T) {
+	t.Setenv("GOWORK", "")
+	root := t.TempDir()
+	goMod := "module github.com/acme/mono\n\ngo 1.22\n"
+	writeFileForTest(t, filepath.Join(root, "go.mod"), goMod)
+	writeFileForTest(t, filepath.Join(root, "go.work"), "go 1.22\n\nuse (\n\t.\n\t./services/billing\n)\n")
+	workFile := filepath.Join(root, "go.work")
+
+	var output bytes.Buffer
+	billingDir := filepath.Join(root, "services", "billing")
+	if err := executeRootForTest(&output, "new", billingDir, "--db", "sqlite", "--dry-run"); err != nil {
+		t.Fatalf("new --dry-run error = %v\n%s", err, output.String())
+	}
+	if got := output.String(); !strings.Contains(got, "Workspace "+workFile+" already uses "+billingDir+"\n") ||
+		strings.Contains(got, "Would add") {
+		t.Fatalf("dry run does not report the existing use directive:\n%s", got)
+	}
+
+	output.Reset()
+	ledgerDir := filepath.Join(root, "services", "ledger")
+	if err := executeRootForTest(&output, "new", ledgerDir, "--db", "sqlite", "--dry-run"); err != nil {
+		t.Fatalf("new --dry-run error = %v\n%s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Would add "+ledgerDir+" to workspace "+workFile+"\n") {
+		t.Fatalf("unexpected output:\n%s", output.String())
+	}
+
+	output.Reset()
+	if err := executeRootForTest(&output, "new", ledgerDir, "--db", "sqlite", "--dry-run", "--no-module"); err != nil {
+		t.Fatalf("new --dry-run --no-module error = %v\n%s", err, output.String())
+	}
+	if !strings.Contains(output.String(), "Would require gorm.io/gorm ") {
+		t.Fatalf("dry run does not report the requirements:\n%s", output.String())
+	}
+	if got := readFileForTest(t, filepath.Join(root, "go.mod")); got != goMod {
+		t.Fatalf("dry run changed the enclosing go.mod:\n%s", got)
+	}
+
+	err := executeRootForTest(nil, "new", root, "--dry-run", "--no-module")
+	if err == nil || !strings.Contains(err.Error(), "is the root of module github.com/acme/mono") {
+		t.Fatalf("new --no-module in the module root error = %v", err)
+	}
 }
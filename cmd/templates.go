@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SisyphusSQ/go-web-starter/internal/scaf_fold"
+)
+
+var lintOverlayFlag []string
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "Tools for template authors",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+var templatesLintCmd = &cobra.Command{
+	Use:   "lint [dir]",
+	Short: "Check a template directory against every --db and component combination",
+	Long: `lint parses every .tmpl file in dir (the built-in templates when omitted),
+renders the tree for every --db and component combination and reports
+unknown fields and functions, files that no combination generates and
+rendered .go files that fail the Go syntax or type checks. It exits with a
+non-zero status when it finds any problem.`,
+	Example: `  go-web-starter templates lint ./templates
+  go-web-starter templates lint --overlay ./company-overlay`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var templateDir string
+		if len(args) == 1 {
+			templateDir = args[0]
+		}
+
+		report, err := scaf_fold.LintTemplates(scaf_fold.Options{
+			TemplateDir: templateDir,
+			Overlays:    lintOverlayFlag,
+		})
+		if err != nil {
+			return fmt.Errorf("lint templates: %w", err)
+		}
+
+		printLintReport(cmd.OutOrStdout(), report)
+		if len(report.Problems) > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("template lint found %d problem(s)", len(report.Problems))
+		}
+		return nil
+	},
+}
+
+func initTemplates() {
+	templatesLintCmd.Flags().StringArrayVar(
+		&lintOverlayFlag,
+		"overlay",
+		nil,
+		"Template directory layered on top of the templates (repeatable, later wins)",
+	)
+
+	templatesCmd.AddCommand(templatesLintCmd)
+	rootCmd.AddCommand(templatesCmd)
+}
+
+func printLintReport(w io.Writer, report scaf_fold.LintReport) {
+	for _, problem := range report.Problems {
+		switch {
+		case problem.Path == "":
+		case problem.Combination == "":
+			fmt.Fprintf(w, "%s:\n", problem.Path)
+		default:
+			fmt.Fprintf(w, "%s (%s):\n", problem.Path, problem.Combination)
+		}
+		for _, line := range strings.Split(problem.Message, "\n") {
+			fmt.Fprintf(w, "    %s\n", strings.TrimSpace(line))
+		}
+	}
+	if len(report.Problems) > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(
+		w,
+		"Checked %d files across %d combinations: %d problem(s)\n",
+		report.Files,
+		report.Combinations,
+		len(report.Problems),
+	)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/SisyphusSQ/go-web-starter/internal/scaf_fold"
+)
+
+var (
+	upgradeDirFlag         string
+	upgradeTemplateDirFlag string
+	upgradeOverlayFlag     []string
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Merge template changes into a generated project",
+	Long: `upgrade re-renders the templates with the options recorded in .go-web-starter.json
+and applies the changes since the recorded render. Files that were not modified
+locally are replaced, modified files are three-way merged, and overlapping
+changes are left as conflict markers. Files without a recorded base get the new
+render as a <file>.rej diff, which has to be merged and deleted before the next
+upgrade.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		result, err := scaf_fold.Upgrade(upgradeDirFlag, scaf_fold.Options{
+			TemplateDir: upgradeTemplateDirFlag,
+			Overlays:    upgradeOverlayFlag,
+		})
+		if err != nil {
+			return fmt.Errorf("upgrade project: %w", err)
+		}
+
+		printUpgradeResult(cmd.OutOrStdout(), result)
+		if result.NeedsResolution() {
+			return fmt.Errorf(
+				"upgrade left %d conflicted and %d rejected files to resolve",
+				len(result.Conflicts),
+				len(result.Rejected),
+			)
+		}
+		return nil
+	},
+}
+
+func initUpgrade() {
+	upgradeCmd.Flags().StringVar(
+		&upgradeDirFlag,
+		"dir",
+		".",
+		"Project directory",
+	)
+	upgradeCmd.Flags().StringVar(
+		&upgradeTemplateDirFlag,
+		"template-dir",
+		"",
+		"Load templates from this directory (default: the one recorded in the lockfile)",
+	)
+	upgradeCmd.Flags().StringArrayVar(
+		&upgradeOverlayFlag,
+		"overlay",
+		nil,
+		"Template directory layered on top of the templates (repeatable, later wins; default: the recorded overlays)",
+	)
+
+	rootCmd.AddCommand(upgradeCmd)
+}
+
+func printUpgradeResult(w io.Writer, result scaf_fold.UpgradeResult) {
+	fmt.Fprintf(w, "Upgraded from %s to %s\n\n", result.FromVersion, result.ToVersion)
+	for _, group := range []struct {
+		label string
+		paths []string
+	}{
+		{"update", result.Updated},
+		{"add", result.Added},
+		{"remove", result.Removed},
+		{"merge", result.Merged},
+		{"conflict", result.Conflicts},
+		{"reject", result.Rejected},
+		{"skip", result.Skipped},
+	} {
+		for _, p := range group.paths {
+			fmt.Fprintf(w, "  %-8s  %s\n", group.label, p)
+		}
+	}
+	if len(result.Rejected) > 0 {
+		fmt.Fprintln(w, "\nRejected files have no recorded base; merge the new template content in their <file>.rej by hand and delete it; upgrade and diff refuse to run while it is left.")
+	}
+
+	total := len(result.Updated) + len(result.Added) + len(result.Removed) +
+		len(result.Merged) + len(result.Conflicts) + len(result.Rejected) + len(result.Skipped)
+	if total == 0 {
+		fmt.Fprintln(w, "  project is up to date")
+	}
+}
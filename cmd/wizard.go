@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+
+	"github.com/SisyphusSQ/go-web-starter/internal/scaf_fold"
+)
+
+// wizardDatabases are the --db values offered by the wizard, numbered from 1.
+var wizardDatabases = []string{"mysql", "postgres", "sqlite", "mongodb"}
+
+// stdinIsTerminal reports whether in is an interactive terminal. Tests
+// replace it to drive the wizard with scripted input.
+var stdinIsTerminal = func(in io.Reader) bool {
+	f, ok := in.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// shouldRunWizard reports whether new/init should prompt for the template
+// data: stdin is a terminal and no flag was given.
+func shouldRunWizard(cmd *cobra.Command) bool {
+	flagGiven := false
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		flagGiven = flagGiven || f.Changed
+	})
+	return !flagGiven && stdinIsTerminal(cmd.InOrStdin())
+}
+
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// runWizard prompts for the module path, binary name, databases and
+// components of the project generated into outputDir. Invalid answers are
+// reported inline and asked again.
+func runWizard(in io.Reader, out io.Writer, outputDir string) (scaf_fold.TemplateData, error) {
+	projectName, err := inferProjectName(outputDir)
+	if err != nil {
+		return scaf_fold.TemplateData{}, err
+	}
+
+	w := wizard{in: bufio.NewReader(in), out: out}
+	fmt.Fprintf(out, "Configure %s (press Enter to accept the default in brackets)\n\n", projectName)
+
+	moduleName, err := w.ask("Go module path", defaultModuleName(outputDir, projectName), scaf_fold.ValidateModulePath)
+	if err != nil {
+		return scaf_fold.TemplateData{}, err
+	}
+	binaryName, err := w.ask("Binary name", projectName, scaf_fold.ValidateBinaryName)
+	if err != nil {
+		return scaf_fold.TemplateData{}, err
+	}
+
+	fmt.Fprintln(out, "\nDatabases: pick one SQL engine, optionally with mongodb")
+	for i, name := range wizardDatabases {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, name)
+	}
+	dbValue, err := w.ask("Databases", "mysql,mongodb", func(answer string) error {
+		_, err := scaf_fold.ParseDBFlag(answer)
+		return err
+	}, wizardDatabases...)
+	if err != nil {
+		return scaf_fold.TemplateData{}, err
+	}
+
+	fmt.Fprintln(out, "\nOptional components:")
+	var componentNames []string
+	for i, component := range scaf_fold.Components() {
+		componentNames = append(componentNames, component.Name)
+		fmt.Fprintf(out, "  %d) %-10s  %s\n", i+1, component.Name, component.Description)
+	}
+	componentValue, err := w.ask(
+		`Components ("none" for none)`,
+		strings.Join(scaf_fold.DefaultComponents(), ","),
+		validateWizardComponents,
+		componentNames...,
+	)
+	if err != nil {
+		return scaf_fold.TemplateData{}, err
+	}
+	fmt.Fprintln(out)
+
+	data, err := buildTemplateData(outputDir, moduleName, binaryName, dbValue)
+	if err != nil {
+		return scaf_fold.TemplateData{}, err
+	}
+	data.Components = wizardComponents(componentValue)
+	return data, nil
+}
+
+// ask prompts for a value until validate accepts it. When choices are given,
+// the answer is a comma-separated list in which numbers select choices by
+// position; it is returned as names.
+func (w wizard) ask(label, defaultValue string, validate func(string) error, choices ...string) (string, error) {
+	for {
+		fmt.Fprintf(w.out, "%s [%s]: ", label, defaultValue)
+		line, err := w.in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			fmt.Fprintln(w.out)
+			if errors.Is(err, io.EOF) {
+				return "", fmt.Errorf("wizard: input ended before %s was answered", strings.ToLower(label))
+			}
+			return "", fmt.Errorf("wizard: read %s: %w", strings.ToLower(label), err)
+		}
+
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = defaultValue
+		}
+		if len(choices) > 0 {
+			if answer, err = resolveWizardChoices(answer, choices); err != nil {
+				fmt.Fprintf(w.out, "  %v\n", err)
+				continue
+			}
+		}
+		if err := validate(answer); err != nil {
+			fmt.Fprintf(w.out, "  %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// resolveWizardChoices replaces the choice numbers of a comma-separated
+// answer with the choice names.
+func resolveWizardChoices(answer string, choices []string) (string, error) {
+	tokens := strings.Split(answer, ",")
+	for i, token := range tokens {
+		token = strings.TrimSpace(token)
+		n, err := strconv.Atoi(token)
+		if err != nil {
+			tokens[i] = token
+			continue
+		}
+		if n < 1 || n > len(choices) {
+			return "", fmt.Errorf("invalid choice %d: pick 1-%d", n, len(choices))
+		}
+		tokens[i] = choices[n-1]
+	}
+	return strings.Join(tokens, ","), nil
+}
+
+func validateWizardComponents(answer string) error {
+	if strings.EqualFold(strings.TrimSpace(answer), "none") {
+		return nil
+	}
+	for _, token := range strings.Split(answer, ",") {
+		name := strings.ToLower(strings.TrimSpace(token))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, component := range scaf_fold.Components() {
+			known = known || component.Name == name
+		}
+		if !known {
+			return fmt.Errorf("unknown component %q: choose from %s or none", token, componentFlagUsage())
+		}
+	}
+	return nil
+}
+
+// wizardComponents returns the components selected by a validated answer in
+// registry order.
+func wizardComponents(answer string) []string {
+	selected := make(map[string]bool)
+	for _, token := range strings.Split(answer, ",") {
+		selected[strings.ToLower(strings.TrimSpace(token))] = true
+	}
+
+	names := make([]string, 0)
+	for _, component := range scaf_fold.Components() {
+		if selected[component.Name] {
+			names = append(names, component.Name)
+		}
+	}
+	return names
+}
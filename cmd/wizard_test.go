@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func stubTerminalForTest(t *testing.T) {
+	t.Helper()
+	original := stdinIsTerminal
+	stdinIsTerminal = func(io.Reader) bool { return true }
+	t.Cleanup(func() {
+		stdinIsTerminal = original
+	})
+}
+
+func TestRunWizardDefaults(t *testing.T) {
+	var output bytes.Buffer
+	data, err := runWizard(strings.NewReader("\n\n\n\n"), &output, filepath.Join(t.TempDir(), "demo-wizard"))
+	if err != nil {
+		t.Fatalf("runWizard() error = %v", err)
+	}
+
+	if data.ModuleName != "example.com/demo-wizard" || data.BinaryName != "demo-wizard" {
+		t.Fatalf("runWizard() module/binary = %q/%q", data.ModuleName, data.BinaryName)
+	}
+	if !data.MySQL || !data.MongoDB || data.Postgres || data.SQLite {
+		t.Fatalf("runWizard() databases = %+v, want mysql,mongodb", data)
+	}
+	if want := []string{"redis", "cron", "lark", "prometheus", "jwt"}; !reflect.DeepEqual(data.Components, want) {
+		t.Fatalf("runWizard() components = %v, want %v", data.Components, want)
+	}
+	for _, want := range []string{
+		"Go module path [example.com/demo-wizard]: ",
+		"Binary name [demo-wizard]: ",
+		"  3) sqlite\n",
+		"Components (\"none\" for none) [redis,cron,lark,prometheus,jwt]: ",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Fatalf("wizard output missing %q:\n%s", want, output.String())
+		}
+	}
+}
+
+func TestRunWizardReportsInvalidAnswersInline(t *testing.T) {
+	script := strings.Join([]string{
+		"not a module",
+		"github.com/acme/orders",
+		"bad/name",
+		"orders-api",
+		"mysql,sqlite",
+		"9",
+		"3",
+		"kafka",
+		"1,jwt",
+	}, "\n") + "\n"
+
+	var output bytes.Buffer
+	data, err := runWizard(strings.NewReader(script), &output, "orders")
+	if err != nil {
+		t.Fatalf("runWizard() error = %v", err)
+	}
+
+	if data.ModuleName != "github.com/acme/orders" || data.BinaryName != "orders-api" {
+		t.Fatalf("runWizard() module/binary = %q/%q", data.ModuleName, data.BinaryName)
+	}
+	if !data.SQLite || data.MySQL || data.MongoDB {
+		t.Fatalf("runWizard() databases = %+v, want sqlite", data)
+	}
+	if want := []string{"redis", "jwt"}; !reflect.DeepEqual(data.Components, want) {
+		t.Fatalf("runWizard() components = %v, want %v", data.Components, want)
+	}
+	for _, want := range []string{
+		`  module cannot contain whitespace: "not a module"`,
+		`  binary cannot contain path separators: "bad/name"`,
+		"  mysql, postgres and sqlite cannot be combined: select one SQL database",
+		"  invalid choice 9: pick 1-4",
+		`  unknown component "kafka"`,
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Fatalf("wizard output missing %q:\n%s", want, output.String())
+		}
+	}
+}
+
+func TestRunWizardNoComponents(t *testing.T) {
+	data, err := runWizard(strings.NewReader("\n\npostgres\nnone\n"), io.Discard, "demo")
+	if err != nil {
+		t.Fatalf("runWizard() error = %v", err)
+	}
+	if data.Components == nil || len(data.Components) != 0 {
+		t.Fatalf("runWizard() components = %#v, want an empty selection", data.Components)
+	}
+}
+
+func TestRunWizardFailsOnEndOfInput(t *testing.T) {
+	_, err := runWizard(strings.NewReader("\n"), io.Discard, "demo")
+	if err == nil || !strings.Contains(err.Error(), "input ended before binary name was answered") {
+		t.Fatalf("runWizard() error = %v", err)
+	}
+}
+
+func TestRootExecuteNewRunsWizardOnTerminal(t *testing.T) {
+	stubTerminalForTest(t)
+	outDir := filepath.Join(t.TempDir(), "demo-wizard")
+
+	var output bytes.Buffer
+	if err := executeRootWithInputForTest(&output, "github.com/acme/demo\n\nmongodb\nnone\n", "new", outDir); err != nil {
+		t.Fatalf("execute new with wizard failed: %v", err)
+	}
+
+	goMod, err := os.ReadFile(filepath.Join(outDir, "go.mod"))
+	if err != nil {
+		t.Fatalf("read generated go.mod: %v", err)
+	}
+	if !strings.Contains(string(goMod), "module github.com/acme/demo\n") {
+		t.Fatalf("go.mod should use the module path from the wizard:\n%s", goMod)
+	}
+	if strings.Contains(string(goMod), "gorm.io/gorm") {
+		t.Fatalf("mongodb-only wizard answer should not include gorm:\n%s", goMod)
+	}
+}
+
+func TestRootExecuteNewSkipsWizardWithFlags(t *testing.T) {
+	stubTerminalForTest(t)
+	outDir := filepath.Join(t.TempDir(), "demo-flags")
+
+	var output bytes.Buffer
+	if err := executeRootWithInputForTest(&output, "", "new", outDir, "--db", "sqlite"); err != nil {
+		t.Fatalf("execute new with flags failed: %v", err)
+	}
+	if strings.Contains(output.String(), "Go module path") {
+		t.Fatalf("wizard should not run when flags are given:\n%s", output.String())
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/SisyphusSQ/go-web-starter/internal/scaf_fold"
+)
+
+// joinWorkspace adds the module generated at projectDir to the go.work of
+// ws, the workspace found around projectDir before generating, or the
+// requirements of a --no-module project to the enclosing go.mod, and reports
+// how the project relates to an enclosing module.
+func joinWorkspace(
+	w io.Writer,
+	projectDir string,
+	data scaf_fold.TemplateData,
+	opts scaf_fold.Options,
+	ws scaf_fold.Workspace,
+) error {
+	switch {
+	case data.NoModule:
+		fmt.Fprintf(w, "Generated as package tree %s of module %s (no go.mod created)\n", data.ModuleName, ws.ModulePath)
+		changed, err := scaf_fold.AddModuleRequirements(ws.ModFile, data, opts)
+		if err != nil {
+			return fmt.Errorf("project generated, but updating %s failed: %w", ws.ModFile, err)
+		}
+		for _, req := range changed {
+			fmt.Fprintf(w, "  require  %s\n", req)
+		}
+		fmt.Fprintln(w)
+	case ws.WorkFile != "":
+		added, err := scaf_fold.AddWorkUse(ws.WorkFile, projectDir)
+		if err != nil {
+			return fmt.Errorf("project generated, but updating go.work failed: %w", err)
+		}
+		if added {
+			fmt.Fprintf(w, "Added %s to workspace %s\n\n", projectDir, ws.WorkFile)
+		} else {
+			fmt.Fprintf(w, "Workspace %s already uses %s\n\n", ws.WorkFile, projectDir)
+		}
+	case ws.ModFile != "" && !isModuleRoot(ws, projectDir):
+		fmt.Fprintf(
+			w,
+			"Note: %s is inside module %s but has its own go.mod; use --no-module to generate into that module\n\n",
+			projectDir,
+			ws.ModulePath,
+		)
+	}
+	return nil
+}
+
+// printWorkspacePlan reports the go.work or go.mod changes that
+// joinWorkspace would make.
+func printWorkspacePlan(
+	w io.Writer,
+	projectDir string,
+	data scaf_fold.TemplateData,
+	opts scaf_fold.Options,
+	ws scaf_fold.Workspace,
+) error {
+	switch {
+	case data.NoModule:
+		changed, err := scaf_fold.PlanModuleRequirements(ws.ModFile, data, opts)
+		if err != nil {
+			return fmt.Errorf("plan requirements of %s: %w", ws.ModFile, err)
+		}
+		for _, req := range changed {
+			fmt.Fprintf(w, "Would require %s in %s\n", req, ws.ModFile)
+		}
+	case ws.WorkFile != "":
+		uses, err := scaf_fold.WorkUses(ws.WorkFile, projectDir)
+		if err != nil {
+			return err
+		}
+		if uses {
+			fmt.Fprintf(w, "Workspace %s already uses %s\n", ws.WorkFile, projectDir)
+		} else {
+			fmt.Fprintf(w, "Would add %s to workspace %s\n", projectDir, ws.WorkFile)
+		}
+	}
+	return nil
+}
+
+func isModuleRoot(ws scaf_fold.Workspace, dir string) bool {
+	absDir, err := filepath.Abs(dir)
+	return err == nil && filepath.Dir(ws.ModFile) == absDir
+}
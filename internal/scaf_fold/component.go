@@ -0,0 +1,285 @@
+package scaf_fold
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Component is an optional feature of the generated project.
+//
+// A component owns:
+//   - its template paths, through manifest.yaml rules such as
+//     `when: .Has "cron"`;
+//   - its go.mod requirements, listed in Modules and rendered by go.mod.tmpl
+//     through TemplateData.ComponentModules;
+//   - its config sections and fx module registrations, guarded in the shared
+//     templates with `{{ if .Has "cron" }}`.
+type Component struct {
+	Name        string
+	Description string
+	// Default components are enabled unless deselected with --without.
+	Default bool
+	// Requires lists components that must be enabled alongside this one.
+	Requires []string
+	// Uses lists components this one integrates with when they are enabled
+	// as well; without them it falls back to a built-in alternative, such as
+	// jwt validating tokens statelessly when redis is disabled.
+	Uses []string
+	// Modules are go.mod require lines ("path version") added when the
+	// component is enabled.
+	Modules []string
+	// PairedModules are go.mod require lines added only when the keyed
+	// component, one of Uses, is enabled as well.
+	PairedModules map[string][]string
+}
+
+var components = []Component{
+	{
+		Name:        "redis",
+		Description: "Redis client (go-redis v9)",
+		Default:     true,
+		Modules: []string{
+			"github.com/redis/go-redis/v9 v9.17.3",
+		},
+	},
+	{
+		Name:        "cron",
+		Description: "Cron scheduler, with Redis distributed locks when redis is enabled",
+		Default:     true,
+		Uses:        []string{"redis"},
+		Modules: []string{
+			"github.com/robfig/cron/v3 v3.0.1",
+		},
+		PairedModules: map[string][]string{
+			"redis": {"github.com/bsm/redislock v0.9.4"},
+		},
+	},
+	{
+		Name:        "lark",
+		Description: "Lark (Feishu) messaging service",
+		Default:     true,
+		Modules: []string{
+			"github.com/larksuite/oapi-sdk-go/v3 v3.5.3",
+		},
+	},
+	{
+		Name:        "prometheus",
+		Description: "Prometheus HTTP metrics and query service",
+		Default:     true,
+		Modules: []string{
+			"github.com/labstack/echo-contrib v0.50.1",
+			"github.com/prometheus/client_golang v1.23.2",
+			"github.com/prometheus/common v0.67.5",
+		},
+	},
+	{
+		Name:        "jwt",
+		Description: "JWT auth middleware with login/logout example; tokens are revocable when redis is enabled",
+		Default:     true,
+		Uses:        []string{"redis"},
+		Modules: []string{
+			"github.com/golang-jwt/jwt/v5 v5.3.1",
+		},
+	},
+}
+
+// Components returns the registered components in registry order.
+func Components() []Component {
+	out := make([]Component, len(components))
+	copy(out, components)
+	return out
+}
+
+// DefaultComponents returns the names of the components enabled when no
+// --with/--without flags are given.
+func DefaultComponents() []string {
+	var names []string
+	for _, c := range components {
+		if c.Default {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func lookupComponent(name string) (Component, bool) {
+	for _, c := range components {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Component{}, false
+}
+
+func componentNames() string {
+	names := make([]string, 0, len(components))
+	for _, c := range components {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseComponentFlags resolves --with and --without values against the
+// default component set and returns the enabled component names in
+// registry order.
+func ParseComponentFlags(with, without string) ([]string, error) {
+	return ApplyComponentFlags(DefaultComponents(), with, without)
+}
+
+// ApplyComponentFlags resolves --with and --without values against the
+// component set base, such as the components of a preset.
+func ApplyComponentFlags(base []string, with, without string) ([]string, error) {
+	if err := validateComponentNames(base); err != nil {
+		return nil, err
+	}
+	withSet, err := parseComponentList("with", with)
+	if err != nil {
+		return nil, err
+	}
+	withoutSet, err := parseComponentList("without", without)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := make(map[string]bool)
+	for _, name := range base {
+		enabled[name] = true
+	}
+	for name := range withSet {
+		if withoutSet[name] {
+			return nil, fmt.Errorf("component %q cannot be both in --with and --without", name)
+		}
+		enabled[name] = true
+	}
+	for name := range withoutSet {
+		enabled[name] = false
+	}
+
+	names := make([]string, 0, len(components))
+	for _, c := range components {
+		if enabled[c.Name] {
+			names = append(names, c.Name)
+		}
+	}
+	return names, nil
+}
+
+func parseComponentList(flag, val string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, rawToken := range strings.Split(val, ",") {
+		token := strings.ToLower(strings.TrimSpace(rawToken))
+		if token == "" {
+			continue
+		}
+		if _, ok := lookupComponent(token); !ok {
+			return nil, fmt.Errorf(
+				"invalid --%s value %q: allowed values are %s",
+				flag,
+				rawToken,
+				componentNames(),
+			)
+		}
+		set[token] = true
+	}
+	return set, nil
+}
+
+// enabledComponents returns the selected component names, falling back to
+// the defaults when Components was never set.
+func (d TemplateData) enabledComponents() []string {
+	if d.Components == nil {
+		return DefaultComponents()
+	}
+	return d.Components
+}
+
+// Has reports whether the named component is enabled. It fails for names
+// that are not registered so that template typos surface as render errors.
+func (d TemplateData) Has(name string) (bool, error) {
+	if _, ok := lookupComponent(name); !ok {
+		return false, fmt.Errorf("unknown component %q (known: %s)", name, componentNames())
+	}
+	for _, enabled := range d.enabledComponents() {
+		if enabled == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ComponentModules returns the go.mod require lines of every enabled
+// component, including paired modules whose partner is enabled too.
+func (d TemplateData) ComponentModules() []string {
+	names := d.enabledComponents()
+	enabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		enabled[name] = true
+	}
+
+	var modules []string
+	for _, c := range components {
+		if !enabled[c.Name] {
+			continue
+		}
+		modules = append(modules, c.Modules...)
+		for _, partner := range c.Uses {
+			if enabled[partner] {
+				modules = append(modules, c.PairedModules[partner]...)
+			}
+		}
+	}
+	return modules
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateComponentNames checks that every name is a registered component.
+// Partial sets such as the components of a preset are checked this way,
+// since --with may still add what they require.
+func validateComponentNames(names []string) error {
+	for _, name := range names {
+		if _, ok := lookupComponent(name); !ok {
+			return fmt.Errorf("unknown component %q (known: %s)", name, componentNames())
+		}
+	}
+	return nil
+}
+
+// validateComponents checks the final component set: every name must be
+// registered and every enabled component must have its Requires enabled.
+func validateComponents(names []string) error {
+	if err := validateComponentNames(names); err != nil {
+		return err
+	}
+	enabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		enabled[name] = true
+	}
+
+	for _, c := range components {
+		if !enabled[c.Name] {
+			continue
+		}
+		for _, dep := range c.Requires {
+			if !enabled[dep] {
+				return fmt.Errorf(
+					"component %s requires %s: add --with %s or disable it with --without %s",
+					c.Name,
+					dep,
+					dep,
+					c.Name,
+				)
+			}
+		}
+	}
+
+	return nil
+}
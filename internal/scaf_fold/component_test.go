@@ -0,0 +1,278 @@
+package scaf_fold
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseComponentFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		with    string
+		without string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			want: DefaultComponents(),
+		},
+		{
+			name:    "without lark",
+			with:    "redis,cron",
+			without: " Lark ,",
+			want:    []string{"redis", "cron", "prometheus", "jwt"},
+		},
+		{
+			name:    "without several",
+			without: "cron,lark,prometheus,jwt",
+			want:    []string{"redis"},
+		},
+		{
+			name:    "unknown component",
+			with:    "kafka",
+			wantErr: `invalid --with value "kafka"`,
+		},
+		{
+			name:    "both with and without",
+			with:    "cron",
+			without: "cron",
+			wantErr: "cannot be both",
+		},
+		{
+			name:    "without redis",
+			without: "redis",
+			want:    []string{"cron", "lark", "prometheus", "jwt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseComponentFlags(tt.with, tt.without)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseComponentFlags() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseComponentFlags() error = %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("ParseComponentFlags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateDataValidateComponents(t *testing.T) {
+	data := TemplateData{
+		ModuleName:  "github.com/test/components",
+		BinaryName:  "components",
+		ProjectName: "components",
+		MySQL:       true,
+		Components:  []string{"jwt"},
+	}
+	if err := data.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v, jwt should not need redis", err)
+	}
+
+	data.Components = []string{"redis", "unknown"}
+	err := data.Validate()
+	if err == nil || !strings.Contains(err.Error(), `unknown component "unknown"`) {
+		t.Fatalf("Validate() error = %v, want unknown component error", err)
+	}
+}
+
+func TestValidateComponentsRequires(t *testing.T) {
+	saved := components
+	t.Cleanup(func() { components = saved })
+	components = append(Components(), Component{Name: "queue", Requires: []string{"redis"}})
+
+	if err := validateComponents([]string{"redis", "queue"}); err != nil {
+		t.Fatalf("validateComponents() error = %v", err)
+	}
+	err := validateComponents([]string{"queue"})
+	want := "component queue requires redis: add --with redis or disable it with --without queue"
+	if err == nil || err.Error() != want {
+		t.Fatalf("validateComponents() error = %v, want %q", err, want)
+	}
+
+	// A preset may list queue alone as long as --with adds redis.
+	got, err := ApplyComponentFlags([]string{"queue"}, "redis", "")
+	if err != nil {
+		t.Fatalf("ApplyComponentFlags() error = %v", err)
+	}
+	if err := validateComponents(got); err != nil {
+		t.Fatalf("validateComponents(%v) error = %v", got, err)
+	}
+}
+
+func TestComponentDependenciesAreRegistered(t *testing.T) {
+	for _, c := range Components() {
+		for _, dep := range append(append([]string(nil), c.Requires...), c.Uses...) {
+			if _, ok := lookupComponent(dep); !ok || dep == c.Name {
+				t.Errorf("component %s depends on invalid component %q", c.Name, dep)
+			}
+		}
+		for partner := range c.PairedModules {
+			if !slices.Contains(c.Uses, partner) {
+				t.Errorf("component %s pairs modules with %s, which is not listed in Uses", c.Name, partner)
+			}
+		}
+	}
+}
+
+func TestTemplateDataHas(t *testing.T) {
+	data := TemplateData{Components: []string{"redis", "cron"}}
+	if ok, err := data.Has("cron"); err != nil || !ok {
+		t.Fatalf(`Has("cron") = %v, %v, want true`, ok, err)
+	}
+	if ok, err := data.Has("lark"); err != nil || ok {
+		t.Fatalf(`Has("lark") = %v, %v, want false`, ok, err)
+	}
+	if _, err := data.Has("kafka"); err == nil {
+		t.Fatal(`Has("kafka") expected error for unregistered component`)
+	}
+
+	var defaults TemplateData
+	for _, name := range DefaultComponents() {
+		if ok, err := defaults.Has(name); err != nil || !ok {
+			t.Fatalf("nil Components should enable default %s, got %v, %v", name, ok, err)
+		}
+	}
+}
+
+func TestEveryComponentOwnsTemplates(t *testing.T) {
+	set, err := loadTemplateSet(Options{})
+	if err != nil {
+		t.Fatalf("loadTemplateSet() error = %v", err)
+	}
+
+	for _, component := range Components() {
+		if len(component.Modules) == 0 {
+			t.Errorf("component %s declares no go.mod requirements", component.Name)
+		}
+
+		marker := `.Has "` + component.Name + `"`
+		owned := false
+		for _, rule := range set.manifest.rules {
+			if strings.Contains(rule.When, marker) {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			t.Errorf("component %s is not referenced by any %s rule", component.Name, manifestFile)
+		}
+	}
+}
+
+func TestGenerateWithoutOptionalComponents(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "minimal-web")
+	if err := Generate(outputDir, TemplateData{
+		ModuleName:  "github.com/test/minimal-web",
+		BinaryName:  "minimal-web",
+		ProjectName: "minimal-web",
+		MySQL:       true,
+		MongoDB:     true,
+		Components:  []string{"redis"},
+	}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	for _, relPath := range []string{
+		filepath.Join("internal", "cron"),
+		filepath.Join("internal", "service", "common_srv"),
+		filepath.Join("internal", "lib", "log", "lark_logger.go"),
+		filepath.Join("internal", "repository", "mysql", "my_common", "lark_msg_log.go"),
+		filepath.Join("utils", "jwt.go"),
+	} {
+		assertFileNotExists(t, filepath.Join(outputDir, relPath))
+	}
+	assertFileExists(t, filepath.Join(outputDir, "internal", "lib", "redis", "redis.go"))
+
+	goMod := readFileForAssertion(t, filepath.Join(outputDir, "go.mod"))
+	for _, module := range []string{
+		"github.com/robfig/cron/v3",
+		"github.com/larksuite/oapi-sdk-go/v3",
+		"github.com/prometheus/client_golang",
+		"github.com/golang-jwt/jwt/v5",
+	} {
+		if strings.Contains(goMod, module) {
+			t.Errorf("go.mod should not require %s:\n%s", module, goMod)
+		}
+	}
+	if !strings.Contains(goMod, "github.com/redis/go-redis/v9 v9.17.3") {
+		t.Errorf("go.mod should require go-redis:\n%s", goMod)
+	}
+
+	configYAML := readFileForAssertion(t, filepath.Join(outputDir, "config", "config.yml"))
+	for _, section := range []string{"\ncron:", "\nlark:", "\nprometheus:", "    jwt:"} {
+		if strings.Contains(configYAML, section) {
+			t.Errorf("config.yml should not contain %q:\n%s", section, configYAML)
+		}
+	}
+	if !strings.Contains(configYAML, "    type: basic\n") {
+		t.Errorf("config.yml should fall back to basic auth without jwt:\n%s", configYAML)
+	}
+}
+
+func TestComponentModulesPairedWithRedis(t *testing.T) {
+	const redislock = "github.com/bsm/redislock v0.9.4"
+
+	withRedis := TemplateData{Components: []string{"redis", "cron"}}
+	if !slices.Contains(withRedis.ComponentModules(), redislock) {
+		t.Fatalf("ComponentModules() = %v, want %s with redis and cron", withRedis.ComponentModules(), redislock)
+	}
+
+	withoutRedis := TemplateData{Components: []string{"cron"}}
+	modules := withoutRedis.ComponentModules()
+	if slices.Contains(modules, redislock) {
+		t.Fatalf("ComponentModules() = %v, should not contain %s without redis", modules, redislock)
+	}
+	if !slices.Contains(modules, "github.com/robfig/cron/v3 v3.0.1") {
+		t.Fatalf("ComponentModules() = %v, want robfig/cron for cron", modules)
+	}
+}
+
+func TestGenerateWithoutRedis(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "no-redis-web")
+	if err := Generate(outputDir, TemplateData{
+		ModuleName:  "github.com/test/no-redis-web",
+		BinaryName:  "no-redis-web",
+		ProjectName: "no-redis-web",
+		MySQL:       true,
+		Components:  []string{"cron", "jwt"},
+	}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	assertFileNotExists(t, filepath.Join(outputDir, "internal", "lib", "redis"))
+
+	goMod := readFileForAssertion(t, filepath.Join(outputDir, "go.mod"))
+	for _, module := range []string{"github.com/redis/go-redis/v9", "github.com/bsm/redislock"} {
+		if strings.Contains(goMod, module) {
+			t.Errorf("go.mod should not require %s:\n%s", module, goMod)
+		}
+	}
+
+	configYAML := readFileForAssertion(t, filepath.Join(outputDir, "config", "config.yml"))
+	if strings.Contains(configYAML, "\nredis:") {
+		t.Errorf("config.yml should not contain a redis section:\n%s", configYAML)
+	}
+
+	for _, relPath := range []string{
+		filepath.Join("internal", "http", "server.go"),
+		filepath.Join("internal", "http", "middleware.go"),
+		filepath.Join("internal", "cron", "cron.go"),
+		filepath.Join("internal", "service", "example_srv", "user_service.go"),
+	} {
+		content := readFileForAssertion(t, filepath.Join(outputDir, relPath))
+		if strings.Contains(content, "internal/lib/redis") {
+			t.Errorf("%s should not import the redis package:\n%s", relPath, content)
+		}
+	}
+}
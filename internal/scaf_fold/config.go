@@ -0,0 +1,194 @@
+package scaf_fold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GeneratorConfig holds generator options read from a --config file or a
+// named preset. Empty fields are unset and leave the value to the next
+// source: preset, then config file, then explicit flags.
+type GeneratorConfig struct {
+	// Module is the full module path. ModulePrefix derives it as
+	// <prefix>/<project-name> when Module is empty.
+	Module       string `yaml:"module"`
+	ModulePrefix string `yaml:"modulePrefix"`
+	Binary       string `yaml:"binary"`
+	// DB uses the --db syntax, e.g. "postgres,mongodb".
+	DB        string `yaml:"db"`
+	GoVersion string `yaml:"goVersion"`
+	// Components replaces the default component set; --with and --without
+	// apply on top of it. An empty list disables every component.
+	Components  []string `yaml:"components"`
+	TemplateDir string   `yaml:"templateDir"`
+	Overlays    []string `yaml:"overlays"`
+	// Extra sets template variables like --set; Merge overrides them key by
+	// key.
+	Extra map[string]string `yaml:"extra"`
+}
+
+type presetsDocument struct {
+	Presets map[string]GeneratorConfig `yaml:"presets"`
+}
+
+// LoadConfig reads a generator config file. Relative template paths are
+// resolved against the directory of the file.
+func LoadConfig(path string) (GeneratorConfig, error) {
+	var cfg GeneratorConfig
+	if err := decodeConfigFile(path, &cfg); err != nil {
+		return GeneratorConfig{}, err
+	}
+	if err := cfg.validate(); err != nil {
+		return GeneratorConfig{}, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg.resolvePaths(filepath.Dir(path)), nil
+}
+
+// LoadPreset reads the preset called name from the presets file at path.
+func LoadPreset(path, name string) (GeneratorConfig, error) {
+	var doc presetsDocument
+	if err := decodeConfigFile(path, &doc); err != nil {
+		return GeneratorConfig{}, err
+	}
+
+	cfg, ok := doc.Presets[name]
+	if !ok {
+		available := sortedKeys(doc.Presets)
+		if len(available) == 0 {
+			return GeneratorConfig{}, fmt.Errorf("preset %q not found: %s defines no presets", name, path)
+		}
+		return GeneratorConfig{}, fmt.Errorf(
+			"preset %q not found in %s (available: %s)",
+			name,
+			path,
+			strings.Join(available, ", "),
+		)
+	}
+	if err := cfg.validate(); err != nil {
+		return GeneratorConfig{}, fmt.Errorf("preset %q in %s: %w", name, path, err)
+	}
+	return cfg.resolvePaths(filepath.Dir(path)), nil
+}
+
+// DefaultPresetsPath returns $XDG_CONFIG_HOME/go-web-starter/presets.yaml,
+// falling back to ~/.config when XDG_CONFIG_HOME is unset.
+func DefaultPresetsPath() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("resolve presets file: %w", err)
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	return filepath.Join(configHome, "go-web-starter", "presets.yaml"), nil
+}
+
+// Merge returns c with every field set in over replacing its value. Module
+// and ModulePrefix are replaced together: a layer that sets either one
+// decides the module path, so a prefix is not shadowed by the module of an
+// earlier layer.
+func (c GeneratorConfig) Merge(over GeneratorConfig) GeneratorConfig {
+	if over.Module != "" || over.ModulePrefix != "" {
+		c.Module = over.Module
+		c.ModulePrefix = over.ModulePrefix
+	}
+	if over.Binary != "" {
+		c.Binary = over.Binary
+	}
+	if over.DB != "" {
+		c.DB = over.DB
+	}
+	if over.GoVersion != "" {
+		c.GoVersion = over.GoVersion
+	}
+	if over.Components != nil {
+		c.Components = over.Components
+	}
+	if over.TemplateDir != "" {
+		c.TemplateDir = over.TemplateDir
+	}
+	if over.Overlays != nil {
+		c.Overlays = over.Overlays
+	}
+	if len(over.Extra) > 0 {
+		extra := make(map[string]string, len(c.Extra)+len(over.Extra))
+		for key, value := range c.Extra {
+			extra[key] = value
+		}
+		for key, value := range over.Extra {
+			extra[key] = value
+		}
+		c.Extra = extra
+	}
+	return c
+}
+
+// ModuleFor returns the module path configured for projectName, or "" when
+// neither Module nor ModulePrefix is set.
+func (c GeneratorConfig) ModuleFor(projectName string) string {
+	if c.Module != "" {
+		return c.Module
+	}
+	if c.ModulePrefix != "" {
+		return strings.TrimSuffix(c.ModulePrefix, "/") + "/" + projectName
+	}
+	return ""
+}
+
+func (c GeneratorConfig) validate() error {
+	if c.DB != "" {
+		if _, err := parseDBFlag(c.DB); err != nil {
+			return err
+		}
+	}
+	if err := validateComponentNames(c.Components); err != nil {
+		return err
+	}
+	for key := range c.Extra {
+		if err := validateExtraKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c GeneratorConfig) resolvePaths(baseDir string) GeneratorConfig {
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(baseDir, p)
+	}
+
+	c.TemplateDir = resolve(c.TemplateDir)
+	if c.Overlays != nil {
+		overlays := make([]string, 0, len(c.Overlays))
+		for _, overlay := range c.Overlays {
+			overlays = append(overlays, resolve(overlay))
+		}
+		c.Overlays = overlays
+	}
+	return c
+}
+
+func decodeConfigFile(path string, out any) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package scaf_fold
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "starter.yaml")
+	writeTemplateTreeForTest(t, dir, map[string]string{
+		"starter.yaml": "modulePrefix: github.com/acme/\n" +
+			"db: postgres,mongodb\n" +
+			"components: [jwt, cron]\n" +
+			"templateDir: templates\n" +
+			"overlays: [overlays/team, /abs/overlay]\n",
+	})
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	want := GeneratorConfig{
+		ModulePrefix: "github.com/acme/",
+		DB:           "postgres,mongodb",
+		Components:   []string{"jwt", "cron"},
+		TemplateDir:  filepath.Join(dir, "templates"),
+		Overlays:     []string{filepath.Join(dir, "overlays", "team"), "/abs/overlay"},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Fatalf("LoadConfig() = %#v, want %#v", cfg, want)
+	}
+	if got := cfg.ModuleFor("orders"); got != "github.com/acme/orders" {
+		t.Fatalf("ModuleFor() = %q", got)
+	}
+}
+
+func TestLoadConfigRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "unknown field", content: "database: mysql\n", wantErr: "field database not found"},
+		{name: "invalid db", content: "db: oracle\n", wantErr: `invalid db value "oracle"`},
+		{name: "unknown component", content: "components: [kafka]\n", wantErr: `unknown component "kafka"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTemplateTreeForTest(t, dir, map[string]string{"starter.yaml": tt.content})
+			_, err := LoadConfig(filepath.Join(dir, "starter.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPreset(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "presets.yaml")
+	writeTemplateTreeForTest(t, dir, map[string]string{
+		"presets.yaml": "presets:\n" +
+			"  internal-api:\n    modulePrefix: git.acme.io/svc\n    db: mysql\n    components: []\n" +
+			"  worker:\n    db: mongodb\n",
+	})
+
+	cfg, err := LoadPreset(path, "internal-api")
+	if err != nil {
+		t.Fatalf("LoadPreset() error = %v", err)
+	}
+	want := GeneratorConfig{ModulePrefix: "git.acme.io/svc", DB: "mysql", Components: []string{}}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Fatalf("LoadPreset() = %#v, want %#v", cfg, want)
+	}
+
+	_, err = LoadPreset(path, "missing")
+	if err == nil || !strings.Contains(err.Error(), `preset "missing" not found`) ||
+		!strings.Contains(err.Error(), "(available: internal-api, worker)") {
+		t.Fatalf("LoadPreset(missing) error = %v", err)
+	}
+}
+
+func TestGeneratorConfigMerge(t *testing.T) {
+	base := GeneratorConfig{
+		ModulePrefix: "github.com/acme",
+		Binary:       "api",
+		DB:           "mysql",
+		Components:   []string{"jwt"},
+		Overlays:     []string{"a"},
+		Extra:        map[string]string{"team": "core", "region": "eu"},
+	}
+	got := base.Merge(GeneratorConfig{
+		Module:     "github.com/acme/custom",
+		DB:         "sqlite",
+		Components: []string{},
+		Extra:      map[string]string{"team": "payments"},
+	})
+	want := GeneratorConfig{
+		Module:     "github.com/acme/custom",
+		Binary:     "api",
+		DB:         "sqlite",
+		Components: []string{},
+		Overlays:   []string{"a"},
+		Extra:      map[string]string{"team": "payments", "region": "eu"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Merge() = %#v, want %#v", got, want)
+	}
+	if got.ModuleFor("orders") != "github.com/acme/custom" {
+		t.Fatalf("ModuleFor() should prefer Module over ModulePrefix, got %q", got.ModuleFor("orders"))
+	}
+
+	// A prefix set by a later layer replaces the module of an earlier one.
+	got = got.Merge(GeneratorConfig{ModulePrefix: "git.acme.io/services"})
+	if got.Module != "" || got.ModuleFor("orders") != "git.acme.io/services/orders" {
+		t.Fatalf("Merge(ModulePrefix) = %#v, module for orders %q", got, got.ModuleFor("orders"))
+	}
+	// A layer that sets neither keeps both.
+	if kept := got.Merge(GeneratorConfig{Binary: "orders"}); kept.ModulePrefix != "git.acme.io/services" {
+		t.Fatalf("Merge() without module fields = %#v", kept)
+	}
+}
+
+func TestDefaultPresetsPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	path, err := DefaultPresetsPath()
+	if err != nil {
+		t.Fatalf("DefaultPresetsPath() error = %v", err)
+	}
+	if path != filepath.Join("/xdg", "go-web-starter", "presets.yaml") {
+		t.Fatalf("DefaultPresetsPath() = %q", path)
+	}
+}
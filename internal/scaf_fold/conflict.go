@@ -0,0 +1,205 @@
+package scaf_fold
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictStrategy decides what Generate does with a file that already exists
+// in the output directory with content different from the render.
+type ConflictStrategy string
+
+const (
+	// ConflictFail aborts before anything is written.
+	ConflictFail ConflictStrategy = "fail"
+	// ConflictSkip keeps the existing file.
+	ConflictSkip ConflictStrategy = "skip"
+	// ConflictOverwrite replaces the existing file, moving it to
+	// <path>.orig first, or to <path>.orig.N when that name is taken.
+	ConflictOverwrite ConflictStrategy = "overwrite"
+	// ConflictPrompt asks Options.Confirm whether to overwrite each file.
+	ConflictPrompt ConflictStrategy = "prompt"
+)
+
+// backupSuffix is appended to the path of a file replaced by
+// ConflictOverwrite, next to the .rej files left by upgrade. An existing
+// backup is never replaced; a numbered suffix is used instead.
+const backupSuffix = ".orig"
+
+// ConflictStrategies returns the accepted --on-conflict values.
+func ConflictStrategies() []string {
+	return []string{
+		string(ConflictFail),
+		string(ConflictSkip),
+		string(ConflictOverwrite),
+		string(ConflictPrompt),
+	}
+}
+
+func ParseConflictStrategy(val string) (ConflictStrategy, error) {
+	strategy := ConflictStrategy(strings.ToLower(strings.TrimSpace(val)))
+	switch strategy {
+	case ConflictFail, ConflictSkip, ConflictOverwrite, ConflictPrompt:
+		return strategy, nil
+	case "":
+		return ConflictFail, nil
+	}
+	return "", fmt.Errorf(
+		"unsupported conflict strategy %q, expected one of: %s",
+		val,
+		strings.Join(ConflictStrategies(), ", "),
+	)
+}
+
+// GenerateResult reports what Generate did to each file, by slash-separated
+// path relative to the output directory.
+type GenerateResult struct {
+	// Created files did not exist before.
+	Created []string
+	// Skipped files already existed and were left untouched, either because
+	// they already held the rendered content or because the conflict
+	// strategy kept them.
+	Skipped []string
+	// Overwritten files were replaced by the render; the previous content is
+	// kept in the backup recorded in Backups.
+	Overwritten []string
+	// Backups maps each overwritten file to the file its previous content
+	// was moved to: <path>.orig, or <path>.orig.N when that already exists.
+	Backups map[string]string
+}
+
+type fileAction int
+
+const (
+	actionCreate fileAction = iota
+	actionSkip
+	actionOverwrite
+)
+
+// resolveConflicts compares the file entries with what already exists in
+// outputDir and decides, before anything is written, whether each one is
+// created, skipped or overwritten.
+func resolveConflicts(outputDir string, entries []renderedEntry, opts Options) (map[string]fileAction, error) {
+	strategy := opts.OnConflict
+	if strategy == "" {
+		strategy = ConflictFail
+	}
+
+	actions := make(map[string]fileAction, len(entries))
+	var conflicts []string
+	for _, entry := range entries {
+		outPath := filepath.Join(outputDir, filepath.FromSlash(entry.path))
+		info, err := os.Stat(outPath)
+		if os.IsNotExist(err) {
+			if !entry.isDir {
+				actions[entry.path] = actionCreate
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stat %s: %w", outPath, err)
+		}
+
+		if entry.isDir {
+			if !info.IsDir() {
+				return nil, fmt.Errorf("%s exists and is not a directory", outPath)
+			}
+			continue
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s exists and is a directory", outPath)
+		}
+
+		current, err := os.ReadFile(outPath)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", outPath, err)
+		}
+		if bytes.Equal(current, entry.content) {
+			actions[entry.path] = actionSkip
+			continue
+		}
+
+		switch strategy {
+		case ConflictFail:
+			conflicts = append(conflicts, entry.path)
+		case ConflictSkip:
+			actions[entry.path] = actionSkip
+		case ConflictOverwrite:
+			actions[entry.path] = actionOverwrite
+		case ConflictPrompt:
+			if opts.Confirm == nil {
+				return nil, fmt.Errorf("conflict strategy %q needs a confirmation callback", strategy)
+			}
+			overwrite, err := opts.Confirm(entry.path)
+			if err != nil {
+				return nil, err
+			}
+			actions[entry.path] = actionSkip
+			if overwrite {
+				actions[entry.path] = actionOverwrite
+			}
+		default:
+			return nil, fmt.Errorf("unsupported conflict strategy %q", strategy)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf(
+			"%d file(s) already exist in %s with different content: %s (use --on-conflict=skip, overwrite or prompt)",
+			len(conflicts),
+			outputDir,
+			strings.Join(conflicts, ", "),
+		)
+	}
+	return actions, nil
+}
+
+// backupPaths picks the backup of every overwritten file: the first of
+// <path>.orig, <path>.orig.1, ... that neither exists in outputDir nor is
+// rendered itself.
+func backupPaths(outputDir string, entries []renderedEntry, actions map[string]fileAction) (map[string]string, error) {
+	taken := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		taken[entry.path] = true
+	}
+
+	backups := make(map[string]string)
+	for _, entry := range entries {
+		if entry.isDir || actions[entry.path] != actionOverwrite {
+			continue
+		}
+		for n := 0; ; n++ {
+			backup := entry.path + backupSuffix
+			if n > 0 {
+				backup = fmt.Sprintf("%s.%d", backup, n)
+			}
+			if taken[backup] {
+				continue
+			}
+			outPath := filepath.Join(outputDir, filepath.FromSlash(backup))
+			if _, err := os.Lstat(outPath); err == nil {
+				continue
+			} else if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("stat %s: %w", outPath, err)
+			}
+			taken[backup] = true
+			backups[entry.path] = backup
+			break
+		}
+	}
+	return backups, nil
+}
+
+func (r *GenerateResult) record(relPath string, action fileAction) {
+	switch action {
+	case actionCreate:
+		r.Created = append(r.Created, relPath)
+	case actionSkip:
+		r.Skipped = append(r.Skipped, relPath)
+	case actionOverwrite:
+		r.Overwritten = append(r.Overwritten, relPath)
+	}
+}
@@ -0,0 +1,226 @@
+package scaf_fold
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseConflictStrategy(t *testing.T) {
+	for input, want := range map[string]ConflictStrategy{
+		"":          ConflictFail,
+		"fail":      ConflictFail,
+		" Skip ":    ConflictSkip,
+		"overwrite": ConflictOverwrite,
+		"prompt":    ConflictPrompt,
+	} {
+		got, err := ParseConflictStrategy(input)
+		if err != nil || got != want {
+			t.Errorf("ParseConflictStrategy(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+
+	if _, err := ParseConflictStrategy("merge"); err == nil || !strings.Contains(err.Error(), "fail, skip, overwrite, prompt") {
+		t.Fatalf("ParseConflictStrategy(merge) error = %v", err)
+	}
+}
+
+func TestGenerateConflictStrategies(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"go.mod.tmpl":     "module {{ .ModuleName }}\n",
+		"README.md.tmpl":  "# {{ .ProjectName }}\n",
+		".gitignore.tmpl": "/bin/\n",
+		"docs/notes.md":   "notes\n",
+	})
+
+	tests := []struct {
+		name        string
+		strategy    ConflictStrategy
+		confirm     func(string) (bool, error)
+		want        GenerateResult
+		wantReadme  string
+		wantBackups []string
+	}{
+		{
+			name:     "skip",
+			strategy: ConflictSkip,
+			want: GenerateResult{
+				Created: []string{LockfileName, "docs/notes.md", "go.mod"},
+				Skipped: []string{".gitignore", "README.md"},
+			},
+			wantReadme: "# Hosted readme\n",
+		},
+		{
+			name:     "overwrite",
+			strategy: ConflictOverwrite,
+			want: GenerateResult{
+				Created:     []string{LockfileName, "docs/notes.md", "go.mod"},
+				Skipped:     []string{".gitignore"},
+				Overwritten: []string{"README.md"},
+				Backups:     map[string]string{"README.md": "README.md.orig"},
+			},
+			wantReadme:  "# check-web\n",
+			wantBackups: []string{"README.md"},
+		},
+		{
+			name:     "prompt",
+			strategy: ConflictPrompt,
+			confirm: func(relPath string) (bool, error) {
+				return relPath == "README.md", nil
+			},
+			want: GenerateResult{
+				Created:     []string{LockfileName, "docs/notes.md", "go.mod"},
+				Skipped:     []string{".gitignore"},
+				Overwritten: []string{"README.md"},
+				Backups:     map[string]string{"README.md": "README.md.orig"},
+			},
+			wantReadme:  "# check-web\n",
+			wantBackups: []string{"README.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := filepath.Join(t.TempDir(), "sample")
+			writeTemplateTreeForTest(t, outputDir, map[string]string{
+				"README.md":  "# Hosted readme\n",
+				".gitignore": "/bin/\n",
+			})
+
+			got, err := GenerateWithOptions(outputDir, goCheckDataForTest(), Options{
+				TemplateDir: templateDir,
+				OnConflict:  tt.strategy,
+				Confirm:     tt.confirm,
+			})
+			if err != nil {
+				t.Fatalf("GenerateWithOptions() error = %v", err)
+			}
+			sortResultForTest(&got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("GenerateWithOptions() result = %#v, want %#v", got, tt.want)
+			}
+			if readme := readFileForAssertion(t, filepath.Join(outputDir, "README.md")); readme != tt.wantReadme {
+				t.Fatalf("README.md = %q, want %q", readme, tt.wantReadme)
+			}
+			for _, relPath := range tt.wantBackups {
+				backup := readFileForAssertion(t, filepath.Join(outputDir, relPath+".orig"))
+				if backup != "# Hosted readme\n" {
+					t.Fatalf("%s.orig = %q", relPath, backup)
+				}
+			}
+			if len(tt.wantBackups) == 0 {
+				assertFileNotExists(t, filepath.Join(outputDir, "README.md.orig"))
+			}
+		})
+	}
+}
+
+func TestGenerateConflictPromptErrorWritesNothing(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"go.mod.tmpl":    "module {{ .ModuleName }}\n",
+		"README.md.tmpl": "# {{ .ProjectName }}\n",
+	})
+	outputDir := filepath.Join(t.TempDir(), "sample")
+	writeTemplateTreeForTest(t, outputDir, map[string]string{"README.md": "# Hosted readme\n"})
+
+	_, err := GenerateWithOptions(outputDir, goCheckDataForTest(), Options{
+		TemplateDir: templateDir,
+		OnConflict:  ConflictPrompt,
+		Confirm: func(string) (bool, error) {
+			return false, errors.New("read answer: EOF")
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "read answer: EOF") {
+		t.Fatalf("GenerateWithOptions() error = %v, want prompt error", err)
+	}
+	assertDirEntries(t, outputDir, "README.md")
+}
+
+func TestGenerateRejectsFileOverDirectory(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"go.mod.tmpl":    "module {{ .ModuleName }}\n",
+		"README.md.tmpl": "# {{ .ProjectName }}\n",
+	})
+	outputDir := filepath.Join(t.TempDir(), "sample")
+	if err := os.MkdirAll(filepath.Join(outputDir, "README.md"), 0o755); err != nil {
+		t.Fatalf("mkdir README.md: %v", err)
+	}
+
+	_, err := GenerateWithOptions(outputDir, goCheckDataForTest(), Options{
+		TemplateDir: templateDir,
+		OnConflict:  ConflictOverwrite,
+	})
+	if err == nil || !strings.Contains(err.Error(), "exists and is a directory") {
+		t.Fatalf("GenerateWithOptions() error = %v, want directory error", err)
+	}
+	assertDirEntries(t, outputDir, "README.md")
+}
+
+func TestGenerateOverwriteKeepsExistingBackups(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"go.mod.tmpl":    "module {{ .ModuleName }}\n",
+		"README.md.tmpl": "# {{ .ProjectName }}\n",
+		// A rendered file never doubles as a backup.
+		"README.md.orig.1": "rendered\n",
+	})
+	outputDir := filepath.Join(t.TempDir(), "sample")
+	writeTemplateTreeForTest(t, outputDir, map[string]string{
+		"README.md":      "# Hosted readme\n",
+		"README.md.orig": "# Older backup\n",
+	})
+
+	got, err := GenerateWithOptions(outputDir, goCheckDataForTest(), Options{
+		TemplateDir: templateDir,
+		OnConflict:  ConflictOverwrite,
+	})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions() error = %v", err)
+	}
+	if want := map[string]string{"README.md": "README.md.orig.2"}; !reflect.DeepEqual(got.Backups, want) {
+		t.Fatalf("GenerateWithOptions() backups = %v, want %v", got.Backups, want)
+	}
+	for relPath, want := range map[string]string{
+		"README.md":        "# check-web\n",
+		"README.md.orig":   "# Older backup\n",
+		"README.md.orig.1": "rendered\n",
+		"README.md.orig.2": "# Hosted readme\n",
+	} {
+		if content := readFileForAssertion(t, filepath.Join(outputDir, relPath)); content != want {
+			t.Errorf("%s = %q, want %q", relPath, content, want)
+		}
+	}
+}
+
+func TestMoveStagedEntriesRestoresOverwrittenFiles(t *testing.T) {
+	outputDir := t.TempDir()
+	writeTemplateTreeForTest(t, outputDir, map[string]string{"README.md": "old\n"})
+	stageDir := t.TempDir()
+	writeTemplateTreeForTest(t, stageDir, map[string]string{"README.md": "new\n"})
+
+	entries := []renderedEntry{
+		{path: "README.md", content: []byte("new\n")},
+		{path: "missing.go", content: []byte("package main\n")},
+	}
+	err := moveStagedEntries(stageDir, outputDir, entries, map[string]string{"README.md": "README.md.orig"})
+	if err == nil {
+		t.Fatal("moveStagedEntries() expected error, got nil")
+	}
+	assertDirEntries(t, outputDir, "README.md")
+	if got := readFileForAssertion(t, filepath.Join(outputDir, "README.md")); got != "old\n" {
+		t.Fatalf("README.md = %q, want restored content", got)
+	}
+}
+
+func sortResultForTest(result *GenerateResult) {
+	sort.Strings(result.Created)
+	sort.Strings(result.Skipped)
+	sort.Strings(result.Overwritten)
+}
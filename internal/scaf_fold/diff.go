@@ -0,0 +1,248 @@
+package scaf_fold
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type editKind int
+
+const (
+	editEqual editKind = iota
+	editDelete
+	editInsert
+)
+
+type lineEdit struct {
+	kind editKind
+	line string
+}
+
+// splitLines splits content after every newline. A last line without a
+// newline is kept as is, so joining the lines restores the content.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// matchLines returns, for each line of a, the index of the line of b it is
+// paired with in a longest common subsequence of a and b, or -1.
+func matchLines(a, b []string) []int {
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		match[prefix] = prefix
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		match[len(a)-1-suffix] = len(b) - 1 - suffix
+		suffix++
+	}
+
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			match[prefix+i] = prefix + j
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return match
+}
+
+// diffLines returns the edit script turning a into b.
+func diffLines(a, b []string) []lineEdit {
+	match := matchLines(a, b)
+	var edits []lineEdit
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		switch {
+		case i < len(a) && match[i] < 0:
+			edits = append(edits, lineEdit{kind: editDelete, line: a[i]})
+			i++
+		case j < len(b) && (i == len(a) || match[i] > j):
+			edits = append(edits, lineEdit{kind: editInsert, line: b[j]})
+			j++
+		default:
+			edits = append(edits, lineEdit{kind: editEqual, line: a[i]})
+			i++
+			j++
+		}
+	}
+	return edits
+}
+
+const diffContext = 3
+
+// unifiedDiff returns the unified diff turning from into to, or "" when they
+// are equal.
+func unifiedDiff(fromName, toName string, from, to []byte) string {
+	edits := diffLines(splitLines(from), splitLines(to))
+
+	// fromLine[k] and toLine[k] count the lines before edits[k].
+	fromLine := make([]int, len(edits)+1)
+	toLine := make([]int, len(edits)+1)
+	for k, e := range edits {
+		fromLine[k+1], toLine[k+1] = fromLine[k], toLine[k]
+		if e.kind != editInsert {
+			fromLine[k+1]++
+		}
+		if e.kind != editDelete {
+			toLine[k+1]++
+		}
+	}
+
+	var buf strings.Builder
+	for k := 0; k < len(edits); {
+		if edits[k].kind == editEqual {
+			k++
+			continue
+		}
+
+		start := max(k-diffContext, 0)
+		end := k
+		for end < len(edits) {
+			if edits[end].kind != editEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].kind == editEqual {
+				run++
+			}
+			if run == len(edits) || run-end > 2*diffContext {
+				end = min(end+diffContext, len(edits))
+				break
+			}
+			end = run
+		}
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(
+			&buf,
+			"@@ -%s +%s @@\n",
+			hunkRange(fromLine[start], fromLine[end]-fromLine[start]),
+			hunkRange(toLine[start], toLine[end]-toLine[start]),
+		)
+		for _, e := range edits[start:end] {
+			prefix := " "
+			switch e.kind {
+			case editDelete:
+				prefix = "-"
+			case editInsert:
+				prefix = "+"
+			}
+			buf.WriteString(prefix + e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		k = end
+	}
+
+	return buf.String()
+}
+
+func hunkRange(before, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	default:
+		return fmt.Sprintf("%d,%d", before+1, count)
+	}
+}
+
+// merge3 merges the changes from base to ours and from base to theirs, line
+// by line as diff3 does. Overlapping changes that differ are written between
+// conflict markers, and the number of conflicts is returned with the result.
+func merge3(base, ours, theirs []byte, oursLabel, theirsLabel string) ([]byte, int) {
+	b, o, t := splitLines(base), splitLines(ours), splitLines(theirs)
+	matchOurs, matchTheirs := matchLines(b, o), matchLines(b, t)
+
+	var (
+		out       []string
+		conflicts int
+	)
+	for bi, oi, ti := 0, 0, 0; ; {
+		// The next base line kept by both sides ends the current chunk.
+		sync := bi
+		for sync < len(b) && (matchOurs[sync] < 0 || matchTheirs[sync] < 0) {
+			sync++
+		}
+		oEnd, tEnd := len(o), len(t)
+		if sync < len(b) {
+			oEnd, tEnd = matchOurs[sync], matchTheirs[sync]
+		}
+
+		if sync == bi && oEnd == oi && tEnd == ti {
+			if sync == len(b) {
+				break
+			}
+			out = append(out, b[sync])
+			bi, oi, ti = bi+1, oi+1, ti+1
+			continue
+		}
+
+		baseChunk, oursChunk, theirsChunk := b[bi:sync], o[oi:oEnd], t[ti:tEnd]
+		switch {
+		case slices.Equal(oursChunk, baseChunk):
+			out = append(out, theirsChunk...)
+		case slices.Equal(theirsChunk, baseChunk), slices.Equal(oursChunk, theirsChunk):
+			out = append(out, oursChunk...)
+		default:
+			conflicts++
+			out = append(out, "<<<<<<< "+oursLabel+"\n")
+			out = appendTerminated(out, oursChunk)
+			out = append(out, "=======\n")
+			out = appendTerminated(out, theirsChunk)
+			out = append(out, ">>>>>>> "+theirsLabel+"\n")
+		}
+		bi, oi, ti = sync, oEnd, tEnd
+	}
+
+	return []byte(strings.Join(out, "")), conflicts
+}
+
+// appendTerminated appends lines, adding the missing newline to the last one
+// so that a conflict marker can follow it.
+func appendTerminated(out, lines []string) []string {
+	out = append(out, lines...)
+	if n := len(out); len(lines) > 0 && !strings.HasSuffix(out[n-1], "\n") {
+		out[n-1] += "\n"
+	}
+	return out
+}
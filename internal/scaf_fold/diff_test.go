@@ -0,0 +1,87 @@
+package scaf_fold
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	const base = "a\nb\nc\nd\ne\nf\n"
+	tests := []struct {
+		name          string
+		ours          string
+		theirs        string
+		want          string
+		wantConflicts int
+	}{
+		{
+			name:   "changes on both sides apart",
+			ours:   "a\nB\nc\nd\ne\nf\n",
+			theirs: "a\nb\nc\nd\nE\nf\ng\n",
+			want:   "a\nB\nc\nd\nE\nf\ng\n",
+		},
+		{
+			name:   "same change on both sides",
+			ours:   "a\nb\nX\nd\ne\nf\n",
+			theirs: "a\nb\nX\nd\ne\nf\n",
+			want:   "a\nb\nX\nd\ne\nf\n",
+		},
+		{
+			name:   "deletion and insertion",
+			ours:   "a\nc\nd\ne\nf\n",
+			theirs: "a\nb\nc\nd\ninserted\ne\nf\n",
+			want:   "a\nc\nd\ninserted\ne\nf\n",
+		},
+		{
+			name:          "overlapping changes",
+			ours:          "a\nb\nours\nd\ne\nf\n",
+			theirs:        "a\nb\ntheirs\nd\ne\nf\n",
+			want:          "a\nb\n<<<<<<< local\nours\n=======\ntheirs\n>>>>>>> upstream\nd\ne\nf\n",
+			wantConflicts: 1,
+		},
+		{
+			name:          "missing final newline",
+			ours:          "a\nb\nc\nd\ne\nours",
+			theirs:        "a\nb\nc\nd\ne\ntheirs",
+			want:          "a\nb\nc\nd\ne\n<<<<<<< local\nours\n=======\ntheirs\n>>>>>>> upstream\n",
+			wantConflicts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := merge3([]byte(base), []byte(tt.ours), []byte(tt.theirs), "local", "upstream")
+			if string(got) != tt.want || conflicts != tt.wantConflicts {
+				t.Fatalf("merge3() = %q, %d conflicts, want %q, %d conflicts", got, conflicts, tt.want, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+func TestMerge3WithoutBase(t *testing.T) {
+	got, conflicts := merge3(nil, []byte("same\n"), []byte("same\n"), "local", "upstream")
+	if string(got) != "same\n" || conflicts != 0 {
+		t.Fatalf("merge3() = %q, %d conflicts", got, conflicts)
+	}
+
+	got, conflicts = merge3(nil, []byte("ours\n"), []byte("theirs\n"), "local", "upstream")
+	if want := "<<<<<<< local\nours\n=======\ntheirs\n>>>>>>> upstream\n"; string(got) != want || conflicts != 1 {
+		t.Fatalf("merge3() = %q, %d conflicts, want %q", got, conflicts, want)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"
+	to := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16"
+
+	want := "--- a/f\n+++ b/f\n" +
+		"@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n" +
+		"@@ -13,3 +13,4 @@\n 13\n 14\n 15\n+16\n\\ No newline at end of file\n"
+	if got := unifiedDiff("a/f", "b/f", []byte(from), []byte(to)); got != want {
+		t.Fatalf("unifiedDiff() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := unifiedDiff("a/f", "b/f", []byte(from), []byte(from)); got != "" {
+		t.Fatalf("unifiedDiff() of equal content = %q, want empty", got)
+	}
+	if got, want := unifiedDiff("a/f", "b/f", nil, []byte("x\n")), "--- a/f\n+++ b/f\n@@ -0,0 +1 @@\n+x\n"; got != want {
+		t.Fatalf("unifiedDiff() of new file = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,144 @@
+package scaf_fold
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SisyphusSQ/go-web-starter/vars"
+)
+
+// DriftReport compares a generated project with the templates re-rendered for
+// its recorded options. A file modified locally that also changed upstream is
+// listed in both groups.
+type DriftReport struct {
+	LockedVersion  string
+	StarterVersion string
+	// ModifiedLocally files differ from the render recorded in the lockfile.
+	ModifiedLocally []FileDrift
+	// ChangedUpstream files render differently than recorded in the lockfile,
+	// including files added to or removed from the templates.
+	ChangedUpstream []FileDrift
+	// Missing files are recorded in the lockfile and rendered, but were
+	// deleted from the project. Files added upstream are ChangedUpstream.
+	Missing []FileDrift
+}
+
+// FileDrift is a file of a DriftReport with its unified diff from the
+// re-rendered template to the working tree.
+type FileDrift struct {
+	Path string
+	Diff string
+}
+
+// Empty reports whether the project matches the re-rendered templates.
+func (r DriftReport) Empty() bool {
+	return len(r.ModifiedLocally) == 0 && len(r.ChangedUpstream) == 0 && len(r.Missing) == 0
+}
+
+// Diff re-renders the templates with the options and template directories
+// recorded in the lockfile of the project at projectDir and reports how the
+// working tree differs from them. Like Upgrade, it fails while .rej files of
+// a previous upgrade are left.
+func Diff(projectDir string, opts Options) (DriftReport, error) {
+	lock, err := ReadLockfile(projectDir)
+	if err != nil {
+		return DriftReport{}, err
+	}
+	if err := checkPendingRejects(projectDir, lock); err != nil {
+		return DriftReport{}, err
+	}
+	opts = lock.templateOptions(projectDir, opts)
+	entries, err := renderLocked(lock, opts)
+	if err != nil {
+		return DriftReport{}, err
+	}
+
+	report := DriftReport{LockedVersion: lock.StarterVersion, StarterVersion: vars.AppVersion}
+	rendered := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.isDir {
+			continue
+		}
+		rendered[entry.path] = true
+
+		current, err := readProjectFile(projectDir, entry.path)
+		if err != nil {
+			return DriftReport{}, err
+		}
+		lockedHash, locked := lock.Files[entry.path]
+		if current == nil {
+			drift := FileDrift{
+				Path: entry.path,
+				Diff: unifiedDiff("a/"+entry.path, "/dev/null", entry.content, nil),
+			}
+			// A file the lockfile does not record was added upstream; a
+			// recorded one was deleted locally.
+			if locked {
+				report.Missing = append(report.Missing, drift)
+			}
+			if !locked || lockedHash != HashContent(entry.content) {
+				report.ChangedUpstream = append(report.ChangedUpstream, drift)
+			}
+			continue
+		}
+
+		currentHash := HashContent(current)
+		if currentHash == HashContent(entry.content) {
+			continue
+		}
+		drift := FileDrift{
+			Path: entry.path,
+			Diff: unifiedDiff("a/"+entry.path, "b/"+entry.path, entry.content, current),
+		}
+		if !locked || currentHash != lockedHash {
+			report.ModifiedLocally = append(report.ModifiedLocally, drift)
+		}
+		if !locked || lockedHash != HashContent(entry.content) {
+			report.ChangedUpstream = append(report.ChangedUpstream, drift)
+		}
+	}
+
+	layers := renderedLayers(opts)
+	for _, relPath := range sortedKeys(lock.Files) {
+		if rendered[relPath] {
+			continue
+		}
+		current, err := readProjectFile(projectDir, relPath)
+		if err != nil {
+			return DriftReport{}, err
+		}
+		if current == nil {
+			continue
+		}
+
+		drift := FileDrift{
+			Path: relPath,
+			Diff: unifiedDiff("/dev/null", "b/"+relPath, nil, current),
+		}
+		if HashContent(current) != lock.Files[relPath] {
+			report.ModifiedLocally = append(report.ModifiedLocally, drift)
+		}
+		// Files of a template directory left out of this render were not
+		// removed upstream.
+		if layers[lock.origin(projectDir, relPath)] {
+			report.ChangedUpstream = append(report.ChangedUpstream, drift)
+		}
+	}
+
+	return report, nil
+}
+
+// readProjectFile returns the content of relPath in projectDir, or nil when
+// the file does not exist.
+func readProjectFile(projectDir, relPath string) ([]byte, error) {
+	outPath := filepath.Join(projectDir, filepath.FromSlash(relPath))
+	content, err := os.ReadFile(outPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", outPath, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,103 @@
+package scaf_fold
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	projectDir := generateUpgradeProjectForTest(t, map[string]string{
+		"Makefile":       upgradeBaseMakefile,
+		"README.md.tmpl": "# {{ .ProjectName }}\n",
+		"LICENSE":        "MIT\n",
+		"Dockerfile":     "FROM scratch\n",
+		"NOTICE":         "v1\n",
+		"old.txt":        "old\n",
+	})
+	writeFileForTest(t, filepath.Join(projectDir, "Makefile"), "build:\n\tgo build -v\n")
+	writeFileForTest(t, filepath.Join(projectDir, "LICENSE"), "Apache-2.0\n")
+	for _, name := range []string{"Dockerfile", "NOTICE"} {
+		if err := os.Remove(filepath.Join(projectDir, name)); err != nil {
+			t.Fatalf("remove %s: %v", name, err)
+		}
+	}
+
+	lock, err := ReadLockfile(projectDir)
+	if err != nil {
+		t.Fatalf("ReadLockfile() error = %v", err)
+	}
+	templateDir := resolveRecordedDir(projectDir, lock.TemplateDir)
+	if err := os.RemoveAll(templateDir); err != nil {
+		t.Fatalf("remove %s: %v", templateDir, err)
+	}
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"Makefile":       upgradeBaseMakefile,
+		"README.md.tmpl": "# {{ .ProjectName }}\n\nUpgraded.\n",
+		"LICENSE":        "BSD\n",
+		"Dockerfile":     "FROM scratch\n",
+		"NOTICE":         "v2\n",
+		"new.txt":        "new\n",
+	})
+
+	report, err := Diff(projectDir, Options{})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	assertDriftPaths(t, "ModifiedLocally", report.ModifiedLocally, "LICENSE", "Makefile")
+	// new.txt is new upstream, Dockerfile was deleted locally, and NOTICE was
+	// deleted locally and changed upstream.
+	assertDriftPaths(t, "ChangedUpstream", report.ChangedUpstream, "LICENSE", "NOTICE", "README.md", "new.txt", "old.txt")
+	assertDriftPaths(t, "Missing", report.Missing, "Dockerfile", "NOTICE")
+
+	wantDiffs := map[string]string{
+		"Makefile": "--- a/Makefile\n+++ b/Makefile\n" +
+			"@@ -1,8 +1,2 @@\n build:\n-\tgo build\n-\n-test:\n-\tgo test\n-\n-lint:\n-\tgo vet\n+\tgo build -v\n",
+		"README.md": "--- a/README.md\n+++ b/README.md\n" +
+			"@@ -1,3 +1 @@\n # upgrade-web\n-\n-Upgraded.\n",
+		"old.txt":    "--- /dev/null\n+++ b/old.txt\n@@ -0,0 +1 @@\n+old\n",
+		"Dockerfile": "--- a/Dockerfile\n+++ /dev/null\n@@ -1 +0,0 @@\n-FROM scratch\n",
+		"new.txt":    "--- a/new.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-new\n",
+	}
+	for _, files := range [][]FileDrift{report.ModifiedLocally, report.ChangedUpstream, report.Missing} {
+		for _, file := range files {
+			if want, ok := wantDiffs[file.Path]; ok && file.Diff != want {
+				t.Errorf("diff of %s =\n%s\nwant\n%s", file.Path, file.Diff, want)
+			}
+		}
+	}
+}
+
+func TestDiffOfFreshProjectIsEmpty(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "drift-web")
+	if err := Generate(outputDir, TemplateData{
+		ModuleName:  "github.com/test/drift-web",
+		BinaryName:  "drift-web",
+		ProjectName: "drift-web",
+		SQLite:      true,
+		Components:  []string{"jwt"},
+	}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	report, err := Diff(outputDir, Options{})
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if !report.Empty() {
+		t.Fatalf("Diff() of a fresh project = %+v, want empty", report)
+	}
+}
+
+func assertDriftPaths(t *testing.T, group string, files []FileDrift, want ...string) {
+	t.Helper()
+	got := make([]string, 0, len(files))
+	for _, file := range files {
+		got = append(got, file.Path)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("%s = %v, want %v", group, got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package scaf_fold
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// extraKeyPattern keeps Extra keys usable as {{ .Extra.key }} in templates.
+var extraKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// missingExtraPattern matches the text/template error for a key missing
+// from TemplateData.Extra under missingkey=error.
+var missingExtraPattern = regexp.MustCompile(`at <\.Extra\.([A-Za-z0-9_]+)>: map has no entry for key`)
+
+// ParseSetFlags builds TemplateData.Extra from --set key=value and
+// --set-file key=path values. A --set-file value is the content of the file
+// with one trailing newline removed. Later values override earlier ones.
+func ParseSetFlags(sets, setFiles []string) (map[string]string, error) {
+	extra := make(map[string]string)
+	for _, set := range sets {
+		key, value, err := splitSetFlag("set", set)
+		if err != nil {
+			return nil, err
+		}
+		extra[key] = value
+	}
+	for _, setFile := range setFiles {
+		key, path, err := splitSetFlag("set-file", setFile)
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --set-file value %q: %w", setFile, err)
+		}
+		value := strings.TrimSuffix(string(content), "\n")
+		extra[key] = strings.TrimSuffix(value, "\r")
+	}
+
+	if len(extra) == 0 {
+		return nil, nil
+	}
+	return extra, nil
+}
+
+func splitSetFlag(flag, val string) (string, string, error) {
+	key, value, ok := strings.Cut(val, "=")
+	if !ok {
+		return "", "", fmt.Errorf("invalid --%s value %q: expected key=value", flag, val)
+	}
+	key = strings.TrimSpace(key)
+	if err := validateExtraKey(key); err != nil {
+		return "", "", fmt.Errorf("invalid --%s value %q: %w", flag, val, err)
+	}
+	return key, value, nil
+}
+
+func validateExtraKey(key string) error {
+	if !extraKeyPattern.MatchString(key) {
+		return fmt.Errorf(
+			"invalid template variable name %q (allowed: letters, digits and underscores, not starting with a digit)",
+			key,
+		)
+	}
+	return nil
+}
+
+// explainMissingExtra turns the error of a template referencing a missing
+// Extra key into a message naming the key and the flag that sets it.
+func explainMissingExtra(filePath string, err error) error {
+	m := missingExtraPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return nil
+	}
+	return fmt.Errorf(
+		"execute template %s: template variable %q is not set (pass --set %s=<value>): %w",
+		filePath,
+		m[1],
+		m[1],
+		err,
+	)
+}
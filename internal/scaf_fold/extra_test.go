@@ -0,0 +1,95 @@
+package scaf_fold
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSetFlags(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplateTreeForTest(t, dir, map[string]string{
+		"header.txt": "Copyright Acme\nAll rights reserved.\n",
+	})
+
+	got, err := ParseSetFlags(
+		[]string{"team=payments", "onCall=pay-oncall=primary", "team=orders"},
+		[]string{"licenseHeader=" + filepath.Join(dir, "header.txt")},
+	)
+	if err != nil {
+		t.Fatalf("ParseSetFlags() error = %v", err)
+	}
+	want := map[string]string{
+		"team":          "orders",
+		"onCall":        "pay-oncall=primary",
+		"licenseHeader": "Copyright Acme\nAll rights reserved.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseSetFlags() = %#v, want %#v", got, want)
+	}
+
+	if got, err := ParseSetFlags(nil, nil); err != nil || got != nil {
+		t.Fatalf("ParseSetFlags(nil, nil) = %#v, %v, want nil map", got, err)
+	}
+}
+
+func TestParseSetFlagsRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		sets     []string
+		setFiles []string
+		want     string
+	}{
+		{name: "missing separator", sets: []string{"team"}, want: `invalid --set value "team": expected key=value`},
+		{name: "dashed key", sets: []string{"on-call=x"}, want: `invalid template variable name "on-call"`},
+		{name: "leading digit", sets: []string{"1team=x"}, want: `invalid template variable name "1team"`},
+		{name: "missing file", setFiles: []string{"header=/nonexistent/header.txt"}, want: `invalid --set-file value`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSetFlags(tt.sets, tt.setFiles)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParseSetFlags() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWithExtraVariables(t *testing.T) {
+	overlayDir := t.TempDir()
+	writeTemplateTreeForTest(t, overlayDir, map[string]string{
+		"OWNERS.tmpl": "team: {{ .Extra.team }}\n",
+	})
+	data := TemplateData{
+		ModuleName:  "github.com/test/extra-web",
+		BinaryName:  "extra-web",
+		ProjectName: "extra-web",
+		MongoDB:     true,
+		Extra:       map[string]string{"team": "payments"},
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "extra-web")
+	if _, err := GenerateWithOptions(outputDir, data, Options{Overlays: []string{overlayDir}}); err != nil {
+		t.Fatalf("GenerateWithOptions() error = %v", err)
+	}
+	if got := readFileForAssertion(t, filepath.Join(outputDir, "OWNERS")); got != "team: payments\n" {
+		t.Fatalf("OWNERS = %q", got)
+	}
+	lockfile := readFileForAssertion(t, filepath.Join(outputDir, LockfileName))
+	if !strings.Contains(lockfile, "\"extra\": {\n      \"team\": \"payments\"\n    }") {
+		t.Fatalf("lockfile does not record extra variables:\n%s", lockfile)
+	}
+
+	data.Extra = nil
+	_, err := PlanWithOptions(data, Options{Overlays: []string{overlayDir}})
+	if err == nil {
+		t.Fatal("PlanWithOptions() without --set team should fail")
+	}
+	for _, want := range []string{"OWNERS.tmpl", `template variable "team" is not set`, "--set team=<value>"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("PlanWithOptions() error = %v, want containing %q", err, want)
+		}
+	}
+}
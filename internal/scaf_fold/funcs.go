@@ -0,0 +1,152 @@
+package scaf_fold
+
+import (
+	"errors"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// templateFuncs returns the helper functions available to every template:
+//
+//	pascal   "user_order" -> "UserOrder", initialisms upper-cased ("user_id" -> "UserID")
+//	camel    "user_order" -> "userOrder"
+//	snake    "UserOrder" -> "user_order"
+//	kebab    "UserOrder" -> "user-order"
+//	plural   "user_order" -> "user_orders", inflects the last word only
+//	goIdent  any string -> a valid Go identifier in camel case ("go-web-starter" -> "goWebStarter")
+//	pkgName  any string -> a Go package name ("go-web-starter" -> "gowebstarter")
+//	quote    Go-quoted string literal
+//	default  {{ index .Extra "team" | default "core" }} uses "core" when the value is empty
+//	required {{ required "team is required" (index .Extra "team") }} fails rendering when empty
+//
+// Words are split on '_', '-', '.', '/', spaces and case changes, so the
+// case helpers accept names in any of these styles.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"pascal":   pascalCase,
+		"camel":    camelCase,
+		"snake":    snakeCase,
+		"kebab":    kebabCase,
+		"plural":   pluralWord,
+		"goIdent":  goIdent,
+		"pkgName":  pkgName,
+		"quote":    strconv.Quote,
+		"default":  defaultValue,
+		"required": requiredValue,
+	}
+}
+
+// splitWords splits s into lower-case words, treating runs of capitals as
+// one word ("HTTPServer" -> "http", "server").
+func splitWords(s string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+func snakeCase(s string) string {
+	return strings.Join(splitWords(s), "_")
+}
+
+func kebabCase(s string) string {
+	return strings.Join(splitWords(s), "-")
+}
+
+func pascalCase(s string) string {
+	return upperCamel(snakeCase(s))
+}
+
+func camelCase(s string) string {
+	return lowerCamel(snakeCase(s))
+}
+
+func pluralWord(s string) string {
+	if s == "" {
+		return ""
+	}
+	return pluralize(s)
+}
+
+func goIdent(s string) string {
+	ident := camelCase(s)
+	switch {
+	case ident == "":
+		return "_"
+	case unicode.IsDigit([]rune(ident)[0]):
+		ident = "_" + ident
+	case token.IsKeyword(ident):
+		ident += "_"
+	}
+	return ident
+}
+
+func pkgName(s string) string {
+	name := strings.Join(splitWords(s), "")
+	switch {
+	case name == "":
+		return "_"
+	case unicode.IsDigit([]rune(name)[0]):
+		name = "_" + name
+	case token.IsKeyword(name):
+		name += "_"
+	}
+	return name
+}
+
+func defaultValue(def, val any) any {
+	if isEmptyValue(val) {
+		return def
+	}
+	return val
+}
+
+func requiredValue(msg string, val any) (any, error) {
+	if isEmptyValue(val) {
+		return nil, errors.New(msg)
+	}
+	return val, nil
+}
+
+func isEmptyValue(val any) bool {
+	if val == nil {
+		return true
+	}
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,127 @@
+package scaf_fold
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCaseHelpers(t *testing.T) {
+	tests := []struct {
+		in     string
+		pascal string
+		camel  string
+		snake  string
+		kebab  string
+	}{
+		{in: "user_order", pascal: "UserOrder", camel: "userOrder", snake: "user_order", kebab: "user-order"},
+		{in: "UserOrder", pascal: "UserOrder", camel: "userOrder", snake: "user_order", kebab: "user-order"},
+		{in: "go-web-starter", pascal: "GoWebStarter", camel: "goWebStarter", snake: "go_web_starter", kebab: "go-web-starter"},
+		{in: "HTTPServer", pascal: "HTTPServer", camel: "httpServer", snake: "http_server", kebab: "http-server"},
+		{in: "userID", pascal: "UserID", camel: "userID", snake: "user_id", kebab: "user-id"},
+		{in: "api v2", pascal: "APIV2", camel: "apiV2", snake: "api_v2", kebab: "api-v2"},
+		{in: "", pascal: "", camel: "", snake: "", kebab: ""},
+	}
+
+	for _, tt := range tests {
+		if got := pascalCase(tt.in); got != tt.pascal {
+			t.Errorf("pascal(%q) = %q, want %q", tt.in, got, tt.pascal)
+		}
+		if got := camelCase(tt.in); got != tt.camel {
+			t.Errorf("camel(%q) = %q, want %q", tt.in, got, tt.camel)
+		}
+		if got := snakeCase(tt.in); got != tt.snake {
+			t.Errorf("snake(%q) = %q, want %q", tt.in, got, tt.snake)
+		}
+		if got := kebabCase(tt.in); got != tt.kebab {
+			t.Errorf("kebab(%q) = %q, want %q", tt.in, got, tt.kebab)
+		}
+	}
+}
+
+func TestPluralWord(t *testing.T) {
+	tests := map[string]string{
+		"user_order": "user_orders",
+		"UserOrder":  "UserOrders",
+		"category":   "categories",
+		"day":        "days",
+		"box":        "boxes",
+		"status":     "statuses",
+		"":           "",
+	}
+	for in, want := range tests {
+		if got := pluralWord(in); got != want {
+			t.Errorf("plural(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGoIdent(t *testing.T) {
+	tests := map[string]string{
+		"go-web-starter": "goWebStarter",
+		"user_id":        "userID",
+		"9lives":         "_9lives",
+		"type":           "type_",
+		"--":             "_",
+	}
+	for in, want := range tests {
+		if got := goIdent(in); got != want {
+			t.Errorf("goIdent(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPkgName(t *testing.T) {
+	tests := map[string]string{
+		"go-web-starter": "gowebstarter",
+		"UserOrder":      "userorder",
+		"3d-render":      "_3drender",
+		"func":           "func_",
+		"":               "_",
+	}
+	for in, want := range tests {
+		if got := pkgName(in); got != want {
+			t.Errorf("pkgName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDefaultAndRequired(t *testing.T) {
+	if got := defaultValue("core", ""); got != "core" {
+		t.Fatalf("default(core, \"\") = %v", got)
+	}
+	if got := defaultValue("core", "payments"); got != "payments" {
+		t.Fatalf("default(core, payments) = %v", got)
+	}
+	if got := defaultValue(1, 0); got != 1 {
+		t.Fatalf("default(1, 0) = %v", got)
+	}
+	if got := defaultValue("none", []string(nil)); got != "none" {
+		t.Fatalf("default(none, nil slice) = %v", got)
+	}
+
+	if got, err := requiredValue("team is required", "payments"); err != nil || got != "payments" {
+		t.Fatalf("required(payments) = %v, %v", got, err)
+	}
+	if _, err := requiredValue("team is required", ""); err == nil || err.Error() != "team is required" {
+		t.Fatalf("required(\"\") error = %v", err)
+	}
+}
+
+func TestRenderTemplateFuncs(t *testing.T) {
+	data := TemplateData{ProjectName: "user-order", Extra: map[string]string{"team": "payments"}}
+	raw := `{{ .ProjectName | pascal }} {{ .ProjectName | camel | plural }} {{ .ProjectName | snake | plural }} ` +
+		`{{ .ProjectName | pkgName }} {{ quote .Extra.team }} {{ index .Extra "region" | default "eu" }}`
+
+	got, err := renderTemplate("funcs.tmpl", []byte(raw), data)
+	if err != nil {
+		t.Fatalf("renderTemplate() error = %v", err)
+	}
+	if want := `UserOrder userOrders user_orders userorder "payments" eu`; string(got) != want {
+		t.Fatalf("renderTemplate() = %q, want %q", got, want)
+	}
+
+	_, err = renderTemplate("funcs.tmpl", []byte(`{{ required "region is required" (index .Extra "region") }}`), data)
+	if err == nil || !strings.Contains(err.Error(), "funcs.tmpl") || !strings.Contains(err.Error(), "region is required") {
+		t.Fatalf("renderTemplate() error = %v, want required error naming the template", err)
+	}
+}
@@ -0,0 +1,350 @@
+package scaf_fold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// formatGoSource formats a rendered Go file with go/format. A syntax error
+// is reported with the template it was rendered from and the offending line
+// of the rendered file, since template mistakes such as an unbalanced
+// {{ if }} block only show up after rendering.
+func formatGoSource(templatePath, relPath string, content []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, relPath, content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		var list scanner.ErrorList
+		if !errors.As(err, &list) || len(list) == 0 {
+			return nil, fmt.Errorf("template %s renders invalid Go in %s: %w", templatePath, relPath, err)
+		}
+		first := list[0]
+		err := fmt.Errorf(
+			"template %s renders invalid Go: %s:%d:%d: %s",
+			templatePath,
+			relPath,
+			first.Pos.Line,
+			first.Pos.Column,
+			first.Msg,
+		)
+		if line := renderedLine(content, first.Pos.Line); line != "" {
+			err = fmt.Errorf("%w\n\t%s", err, line)
+		}
+		return nil, err
+	}
+
+	// Sort the imports as gofmt does before printing.
+	ast.SortImports(fset, file)
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, fmt.Errorf("template %s: format %s: %w", templatePath, relPath, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func renderedLine(content []byte, line int) string {
+	lines := strings.Split(string(content), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
+
+// checkGoPackages type-checks the Go packages of the rendered project with
+// go/types, without network access or a module cache. Packages of the
+// project itself are checked from the rendered sources; every other import
+// resolves to an empty stand-in, so only problems that do not depend on
+// third-party or standard library APIs are reported: unused imports, unused
+// variables and undefined identifiers, including undefined names in the
+// project's own packages. sources maps each rendered path to its template.
+//
+// A stand-in is named after its import path, which is not always the real
+// package name: github.com/larksuite/oapi-sdk-go/v3 declares package lark.
+// In a file with unaliased imports of other packages, an undefined
+// qualifier such as lark in lark.NewClient may therefore be the real name of
+// one of them; it is not reported, and neither are the unused unaliased
+// imports of that file.
+func checkGoPackages(modulePath string, entries []renderedEntry, sources map[string]string) error {
+	problems, err := goTypeProblems(modulePath, entries, sources)
+	if err != nil || len(problems) == 0 {
+		return err
+	}
+	lines := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		lines = append(lines, problem.String())
+	}
+	return fmt.Errorf("rendered Go code does not type-check:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// goTypeProblems returns the problems checkGoPackages reports, ordered by
+// position.
+func goTypeProblems(modulePath string, entries []renderedEntry, sources map[string]string) ([]goProblem, error) {
+	c := &goChecker{
+		modulePath:      modulePath,
+		sources:         sources,
+		fset:            token.NewFileSet(),
+		files:           make(map[string]map[string][]byte),
+		checked:         make(map[string]*types.Package),
+		stubs:           make(map[string]*types.Package),
+		qualifiers:      make(map[token.Pos]*ast.Ident),
+		qualifierOf:     make(map[token.Pos]bool),
+		guessedImports:  make(map[string]bool),
+		guessedImportAt: make(map[token.Pos]bool),
+		unresolved:      make(map[string]bool),
+		info:            &types.Info{Uses: make(map[*ast.Ident]types.Object)},
+	}
+	for _, entry := range entries {
+		if entry.isDir || !strings.HasSuffix(entry.path, ".go") || strings.HasSuffix(entry.path, "_test.go") {
+			continue
+		}
+		if _, ok := sources[entry.path]; !ok {
+			continue
+		}
+		dir := path.Dir(entry.path)
+		if c.files[dir] == nil {
+			c.files[dir] = make(map[string][]byte)
+		}
+		c.files[dir][path.Base(entry.path)] = entry.content
+	}
+
+	for _, dir := range sortedKeys(c.files) {
+		if _, err := c.Import(c.importPath(dir)); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		a, b := c.problems[i].pos, c.problems[j].pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.problems, nil
+}
+
+type goChecker struct {
+	modulePath string
+	sources    map[string]string
+	fset       *token.FileSet
+	// files holds the rendered Go files by directory and file name.
+	files   map[string]map[string][]byte
+	checked map[string]*types.Package
+	stubs   map[string]*types.Package
+	// qualifiers maps the selector of each x.Sel expression to x.
+	qualifiers map[token.Pos]*ast.Ident
+	// qualifierOf holds the position of x in each x.Sel expression.
+	qualifierOf map[token.Pos]bool
+	// guessedImports holds the files importing a package outside the project
+	// without a name, so that its stand-in name is only a guess, and
+	// guessedImportAt the positions of those imports.
+	guessedImports  map[string]bool
+	guessedImportAt map[token.Pos]bool
+	// unresolved holds the files with an undefined qualifier that may be
+	// the real name of one of their guessed imports.
+	unresolved map[string]bool
+	info       *types.Info
+	problems   []goProblem
+}
+
+// goProblem is a type error in a rendered file.
+type goProblem struct {
+	template string
+	pos      token.Position
+	msg      string
+}
+
+func (p goProblem) String() string {
+	if p.template == "" {
+		return p.msg
+	}
+	return fmt.Sprintf("template %s: %s:%d:%d: %s", p.template, p.pos.Filename, p.pos.Line, p.pos.Column, p.msg)
+}
+
+func (c *goChecker) importPath(dir string) string {
+	if dir == "." {
+		return c.modulePath
+	}
+	return c.modulePath + "/" + dir
+}
+
+// Import implements types.Importer, checking project packages on first use.
+func (c *goChecker) Import(importPath string) (*types.Package, error) {
+	if pkg, ok := c.checked[importPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through %s", importPath)
+		}
+		return pkg, nil
+	}
+
+	dir, ok := c.projectDir(importPath)
+	if !ok {
+		return c.stub(importPath), nil
+	}
+	c.checked[importPath] = nil
+
+	files, err := c.parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	conf := types.Config{
+		Importer: c,
+		Error: func(err error) {
+			c.report(err)
+		},
+	}
+	pkg, _ := conf.Check(importPath, c.fset, files, c.info)
+	c.checked[importPath] = pkg
+	return pkg, nil
+}
+
+func (c *goChecker) projectDir(importPath string) (string, bool) {
+	if importPath == c.modulePath {
+		_, ok := c.files["."]
+		return ".", ok
+	}
+	dir, ok := strings.CutPrefix(importPath, c.modulePath+"/")
+	if !ok {
+		return "", false
+	}
+	_, ok = c.files[dir]
+	return dir, ok
+}
+
+// parseDir parses the files of dir that the default build context selects.
+func (c *goChecker) parseDir(dir string) ([]*ast.File, error) {
+	ctxt := build.Default
+	ctxt.JoinPath = path.Join
+	ctxt.OpenFile = func(name string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(c.files[path.Dir(name)][path.Base(name)])), nil
+	}
+
+	var files []*ast.File
+	for _, name := range sortedKeys(c.files[dir]) {
+		if match, err := ctxt.MatchFile(dir, name); err != nil || !match {
+			continue
+		}
+		relPath := path.Join(dir, name)
+		file, err := parser.ParseFile(c.fset, relPath, c.files[dir][name], parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: parse %s: %w", c.sources[relPath], relPath, err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok {
+					c.qualifiers[sel.Sel.Pos()] = x
+					c.qualifierOf[x.Pos()] = true
+				}
+			}
+			return true
+		})
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || spec.Name != nil {
+				continue
+			}
+			if _, ok := c.projectDir(importPath); !ok {
+				c.guessedImports[relPath] = true
+				c.guessedImportAt[spec.Pos()] = true
+			}
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// stub returns an empty package standing in for a package outside the
+// project, named the way goimports assumes from its import path.
+func (c *goChecker) stub(importPath string) *types.Package {
+	if pkg, ok := c.stubs[importPath]; ok {
+		return pkg
+	}
+	pkg := types.NewPackage(importPath, assumedPackageName(importPath))
+	pkg.MarkComplete()
+	c.stubs[importPath] = pkg
+	return pkg
+}
+
+func (c *goChecker) isStub(pkg *types.Package) bool {
+	return pkg != nil && c.stubs[pkg.Path()] == pkg
+}
+
+// report records err unless it is caused by a stand-in package.
+func (c *goChecker) report(err error) {
+	typeErr, ok := err.(types.Error)
+	if !ok {
+		c.problems = append(c.problems, goProblem{msg: err.Error()})
+		return
+	}
+	msg := typeErr.Msg
+	pos := c.fset.Position(typeErr.Pos)
+	switch {
+	case strings.Contains(msg, "declared and not used"):
+	case strings.Contains(msg, "imported and not used"):
+		// go/types reports unused imports after the rest of the package,
+		// so unresolved is complete for the file by now.
+		if c.unresolved[pos.Filename] && c.guessedImportAt[typeErr.Pos] {
+			return
+		}
+	case strings.HasPrefix(msg, "undefined: "):
+		if c.selectsStub(typeErr.Pos) {
+			return
+		}
+		if c.qualifierOf[typeErr.Pos] && c.guessedImports[pos.Filename] {
+			c.unresolved[pos.Filename] = true
+			return
+		}
+	default:
+		return
+	}
+
+	c.problems = append(c.problems, goProblem{template: c.sources[pos.Filename], pos: pos, msg: msg})
+}
+
+// selectsStub reports whether the identifier at pos is the selector of a
+// qualified identifier into a stand-in package, such as Context in
+// gin.Context.
+func (c *goChecker) selectsStub(pos token.Pos) bool {
+	x, ok := c.qualifiers[pos]
+	if !ok {
+		return false
+	}
+	pkgName, ok := c.info.Uses[x].(*types.PkgName)
+	return ok && c.isStub(pkgName.Imported())
+}
+
+// assumedPackageName returns the package name goimports assumes for
+// importPath: the last element without a major version suffix or a "go-"
+// prefix, cut at the first character that cannot appear in an identifier.
+func assumedPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			if dir := path.Dir(importPath); dir != "." {
+				base = path.Base(dir)
+			}
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
@@ -0,0 +1,150 @@
+package scaf_fold
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatGoSource(t *testing.T) {
+	got, err := formatGoSource("x.go.tmpl", "x.go", []byte("package x\nimport (\n\"os\"\n\"fmt\"\n)\nvar A=fmt.Sprint(os.Args)\n"))
+	if err != nil {
+		t.Fatalf("formatGoSource() error = %v", err)
+	}
+	want := "package x\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nvar A = fmt.Sprint(os.Args)\n"
+	if string(got) != want {
+		t.Fatalf("formatGoSource() = %q, want %q", got, want)
+	}
+}
+
+func TestPlanReportsGoSyntaxErrors(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"go.mod.tmpl": "module {{ .ModuleName }}\n",
+		"internal/db/db.go.tmpl": "package db\n\nfunc Open() {\n" +
+			"{{- if .MongoDB }}\n\tif true {\n{{- end }}\n\t}\n}\n",
+	})
+
+	_, err := PlanWithOptions(goCheckDataForTest(), Options{TemplateDir: templateDir})
+	if err == nil {
+		t.Fatal("PlanWithOptions() should fail on a rendered syntax error")
+	}
+	for _, want := range []string{"db.go.tmpl renders invalid Go", "internal/db/db.go:5:1", "expected declaration"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("PlanWithOptions() error = %v, want containing %q", err, want)
+		}
+	}
+}
+
+func TestPlanReportsGoTypeErrors(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"go.mod.tmpl":       "module {{ .ModuleName }}\n",
+		"vars/vars.go.tmpl": "package vars\n\nconst AppName = \"demo\"\n",
+		"internal/http/server.go.tmpl": `package http
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/labstack/echo/v4"
+
+	"{{ .ModuleName }}/vars"
+)
+
+func Run(e *echo.Echo) {
+	fmt.Println(vars.AppName, vars.Version, missingHelper())
+	var unused int
+	e.Logger.Info(echo.New().Server.Addr)
+}
+`,
+	})
+
+	_, err := PlanWithOptions(goCheckDataForTest(), Options{TemplateDir: templateDir})
+	if err == nil {
+		t.Fatal("PlanWithOptions() should fail on type errors")
+	}
+	for _, want := range []string{
+		`server.go.tmpl: internal/http/server.go:5:2: "os" imported and not used`,
+		"internal/http/server.go:13:33: undefined: vars.Version",
+		"internal/http/server.go:13:42: undefined: missingHelper",
+		"internal/http/server.go:14:6: declared and not used: unused",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("PlanWithOptions() error = %v, want containing %q", err, want)
+		}
+	}
+	for _, notWant := range []string{"fmt.Println", "echo.New", "echo.Echo"} {
+		if strings.Contains(err.Error(), notWant) {
+			t.Fatalf("PlanWithOptions() reported %s from a package outside the project: %v", notWant, err)
+		}
+	}
+}
+
+func TestPlanAcceptsPackageNamesThatDifferFromTheImportPath(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"go.mod.tmpl": "module {{ .ModuleName }}\n",
+		// github.com/larksuite/oapi-sdk-go/v3 declares package lark.
+		"internal/lark/client.go.tmpl": `package lark
+
+import (
+	"fmt"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+)
+
+func New() *lark.Client {
+	return lark.NewClient(fmt.Sprint("id"), "secret")
+}
+`,
+		"internal/lark/default.go.tmpl": `package lark
+
+import "github.com/larksuite/oapi-sdk-go/v3"
+
+func NewDefault() *lark.Client {
+	return lark.NewClient("id", "secret")
+}
+`,
+	})
+
+	if _, err := PlanWithOptions(goCheckDataForTest(), Options{TemplateDir: templateDir}); err != nil {
+		t.Fatalf("PlanWithOptions() error = %v", err)
+	}
+
+	// An undefined qualifier in a file without unaliased imports outside
+	// the project is still reported.
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"internal/lark/typo.go.tmpl": "package lark\n\nvar _ = larkk.NewClient\n",
+	})
+	_, err := PlanWithOptions(goCheckDataForTest(), Options{TemplateDir: templateDir})
+	if err == nil || !strings.Contains(err.Error(), "internal/lark/typo.go:3:9: undefined: larkk") {
+		t.Fatalf("PlanWithOptions() error = %v, want undefined larkk", err)
+	}
+}
+
+func TestAssumedPackageName(t *testing.T) {
+	tests := map[string]string{
+		"fmt":                              "fmt",
+		"math/rand/v2":                     "rand",
+		"github.com/labstack/echo/v4":      "echo",
+		"github.com/redis/go-redis/v9":     "redis",
+		"gopkg.in/yaml.v3":                 "yaml",
+		"github.com/larksuite/oapi-sdk-go": "oapi",
+		"go.mongodb.org/mongo-driver/bson": "bson",
+		"github.com/natefinch/lumberjack":  "lumberjack",
+	}
+	for importPath, want := range tests {
+		if got := assumedPackageName(importPath); got != want {
+			t.Errorf("assumedPackageName(%q) = %q, want %q", importPath, got, want)
+		}
+	}
+}
+
+func goCheckDataForTest() TemplateData {
+	return TemplateData{
+		ModuleName:  "github.com/test/check-web",
+		BinaryName:  "check-web",
+		ProjectName: "check-web",
+		MySQL:       true,
+	}
+}
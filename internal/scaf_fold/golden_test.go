@@ -0,0 +1,241 @@
+package scaf_fold
+
+import (
+	"bytes"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/SisyphusSQ/go-web-starter/vars"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the snapshots in testdata/golden")
+
+// goldenSuffix keeps the snapshots out of reach of the go tool and git: a
+// snapshot of go.mod or .gitignore would otherwise act as one.
+const goldenSuffix = ".golden"
+
+const goldenRoot = "testdata/golden"
+
+// goldenVersion replaces the starter version in lockfile snapshots so that a
+// release does not change every snapshot.
+const goldenVersion = "(version)"
+
+// goldenCombo is one --db selection with one set of enabled components.
+type goldenCombo struct {
+	name string
+	data TemplateData
+}
+
+// goldenCombos returns every --db selection with the default components, and
+// the default --db selection with each component disabled in turn. Template
+// lint renders every component subset; the snapshots only need to show how
+// each database and each component changes the output.
+func goldenCombos() []goldenCombo {
+	dbSelections := []Databases{
+		{MySQL: true},
+		{Postgres: true},
+		{SQLite: true},
+		{MongoDB: true},
+		{MySQL: true, MongoDB: true},
+		{Postgres: true, MongoDB: true},
+		{SQLite: true, MongoDB: true},
+	}
+	defaults := DefaultComponents()
+
+	var combos []goldenCombo
+	for _, dbs := range dbSelections {
+		combos = append(combos, newGoldenCombo(dbs, defaults))
+	}
+	for _, component := range Components() {
+		var components []string
+		for _, name := range defaults {
+			if name != component.Name {
+				components = append(components, name)
+			}
+		}
+		if len(components) == len(defaults) {
+			continue
+		}
+		combos = append(combos, newGoldenCombo(Databases{MySQL: true, MongoDB: true}, components))
+	}
+	return combos
+}
+
+func newGoldenCombo(dbs Databases, components []string) goldenCombo {
+	return goldenCombo{
+		name: goldenComboName(dbs, components),
+		data: TemplateData{
+			ModuleName:  "github.com/golden/demo",
+			BinaryName:  "demo",
+			ProjectName: "demo",
+			GoVersion:   "1.26.0",
+			MySQL:       dbs.MySQL,
+			Postgres:    dbs.Postgres,
+			SQLite:      dbs.SQLite,
+			MongoDB:     dbs.MongoDB,
+			Components:  append([]string{}, components...),
+		},
+	}
+}
+
+// goldenComboName names a combination like "mysql-mongodb_cron-redis", or
+// "sqlite_none" when no component is enabled.
+func goldenComboName(dbs Databases, components []string) string {
+	var names []string
+	for _, db := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"mysql", dbs.MySQL},
+		{"postgres", dbs.Postgres},
+		{"sqlite", dbs.SQLite},
+		{"mongodb", dbs.MongoDB},
+	} {
+		if db.enabled {
+			names = append(names, db.name)
+		}
+	}
+
+	sorted := append([]string(nil), components...)
+	sort.Strings(sorted)
+	if len(sorted) == 0 {
+		sorted = []string{"none"}
+	}
+	return strings.Join(names, "-") + "_" + strings.Join(sorted, "-")
+}
+
+// TestGoldenSnapshots compares every generated file of every combination
+// with testdata/golden/<combo>. Run with -update to rewrite the snapshots
+// after an intended template change:
+//
+//	go test ./internal/scaf_fold -run TestGoldenSnapshots -update
+func TestGoldenSnapshots(t *testing.T) {
+	combos := goldenCombos()
+	checkStaleGoldenCombos(t, combos)
+
+	for _, combo := range combos {
+		t.Run(combo.name, func(t *testing.T) {
+			t.Parallel()
+			entries, err := renderProject(combo.data, Options{})
+			if err != nil {
+				t.Fatalf("renderProject() error = %v", err)
+			}
+			if entries, err = withLockfile("", combo.data, Options{}, entries); err != nil {
+				t.Fatalf("withLockfile() error = %v", err)
+			}
+			normalizeGoldenLockfile(entries)
+
+			dir := filepath.Join(goldenRoot, combo.name)
+			if *updateGolden {
+				if err := os.RemoveAll(dir); err != nil {
+					t.Fatalf("remove %s: %v", dir, err)
+				}
+				writeGoldenForTest(t, dir, entries)
+				return
+			}
+			compareGoldenForTest(t, dir, entries)
+		})
+	}
+}
+
+func normalizeGoldenLockfile(entries []renderedEntry) {
+	for i, entry := range entries {
+		if entry.path == LockfileName {
+			entries[i].content = bytes.Replace(
+				entry.content,
+				[]byte(`"starterVersion": "`+vars.AppVersion+`"`),
+				[]byte(`"starterVersion": "`+goldenVersion+`"`),
+				1,
+			)
+		}
+	}
+}
+
+func writeGoldenForTest(t *testing.T, dir string, entries []renderedEntry) {
+	t.Helper()
+	for _, entry := range entries {
+		if entry.isDir {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(entry.path)+goldenSuffix)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir for %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, entry.content, 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+}
+
+func compareGoldenForTest(t *testing.T, dir string, entries []renderedEntry) {
+	t.Helper()
+	golden := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return walkErr
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		golden[strings.TrimSuffix(filepath.ToSlash(rel), goldenSuffix)] = content
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("read snapshots in %s (run with -update to create them): %v", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.isDir {
+			continue
+		}
+		want, ok := golden[entry.path]
+		delete(golden, entry.path)
+		switch {
+		case !ok:
+			t.Errorf("%s is generated but has no snapshot", entry.path)
+		case !bytes.Equal(entry.content, want):
+			t.Errorf("%s differs from its snapshot:\n%s", entry.path,
+				unifiedDiff("golden/"+entry.path, "generated/"+entry.path, want, entry.content))
+		}
+	}
+	for _, relPath := range sortedKeys(golden) {
+		t.Errorf("%s has a snapshot but is no longer generated", relPath)
+	}
+}
+
+// checkStaleGoldenCombos reports snapshot directories that no combination
+// renders any more, or removes them with -update.
+func checkStaleGoldenCombos(t *testing.T, combos []goldenCombo) {
+	t.Helper()
+	dirs, err := os.ReadDir(goldenRoot)
+	if err != nil && !(*updateGolden && os.IsNotExist(err)) {
+		t.Fatalf("read %s (run with -update to create the snapshots): %v", goldenRoot, err)
+	}
+	known := make(map[string]bool, len(combos))
+	for _, combo := range combos {
+		known[combo.name] = true
+	}
+	for _, dir := range dirs {
+		if known[dir.Name()] {
+			continue
+		}
+		stale := filepath.Join(goldenRoot, dir.Name())
+		if !*updateGolden {
+			t.Errorf("%s does not match any combination; rerun with -update", stale)
+			continue
+		}
+		if err := os.RemoveAll(stale); err != nil {
+			t.Fatalf("remove %s: %v", stale, err)
+		}
+	}
+}
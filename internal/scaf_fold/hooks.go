@@ -0,0 +1,221 @@
+package scaf_fold
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/SisyphusSQ/go-web-starter/vars"
+)
+
+// Post-generation hooks, run in this order by RunHooks.
+const (
+	HookTidy = "tidy"
+	HookFmt  = "fmt"
+	HookGit  = "git"
+)
+
+var hookOrder = []string{HookTidy, HookFmt, HookGit}
+
+// CommandRunner runs the external commands of the post-generation hooks.
+type CommandRunner interface {
+	// Run runs name with args in dir and returns its combined output.
+	Run(ctx context.Context, dir string, name string, args ...string) ([]byte, error)
+}
+
+// ExecRunner runs commands with os/exec.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, dir string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	return cmd.CombinedOutput()
+}
+
+// HookResult reports what a hook did. Skipped hooks ran no command.
+type HookResult struct {
+	Hook     string
+	Commands []string
+	Skipped  bool
+	Reason   string
+}
+
+// DefaultHooks returns the hooks run when --hooks is not given: none.
+// Rendered Go files are already formatted, tidy needs the network and git
+// creates a repository, so every hook is opt-in.
+func DefaultHooks() []string {
+	return nil
+}
+
+// ParseHooksFlag parses a comma-separated list of hook names. The result is
+// in the order RunHooks runs them, whatever the order in val.
+func ParseHooksFlag(val string) ([]string, error) {
+	selected := make(map[string]bool)
+	for _, rawToken := range strings.Split(val, ",") {
+		token := strings.ToLower(strings.TrimSpace(rawToken))
+		if token == "" {
+			continue
+		}
+		if !isHook(token) {
+			return nil, fmt.Errorf(
+				"invalid hook %q: allowed values are %s",
+				rawToken,
+				strings.Join(hookOrder, ","),
+			)
+		}
+		selected[token] = true
+	}
+
+	hooks := make([]string, 0, len(selected))
+	for _, hook := range hookOrder {
+		if selected[hook] {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks, nil
+}
+
+func isHook(name string) bool {
+	for _, hook := range hookOrder {
+		if hook == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RunHooks runs hooks in projectDir with runner and stops at the first
+// failing command:
+//
+//	tidy  go mod tidy
+//	fmt   gofmt -l -w on the .go files of paths
+//	git   git init, git add of paths and an initial commit; skipped when
+//	      the project already has a .git directory or is inside a repository
+//
+// paths are the generated files, relative to projectDir, so that files that
+// were in the directory before are never formatted or committed; go.sum is
+// added to the commit when tidy created it.
+func RunHooks(ctx context.Context, projectDir string, hooks []string, paths []string, runner CommandRunner) ([]HookResult, error) {
+	var results []HookResult
+	ranTidy := false
+	for _, hook := range hooks {
+		var commands [][]string
+		// display replaces the command lines reported for commands, keyed by
+		// index, when the literal line would be too long to read.
+		display := make(map[int]string)
+		switch hook {
+		case HookTidy:
+			commands = [][]string{{"go", "mod", "tidy"}}
+			ranTidy = true
+		case HookFmt:
+			goFiles := generatedGoFiles(paths)
+			if len(goFiles) == 0 {
+				results = append(results, HookResult{Hook: hook, Skipped: true, Reason: "no generated Go files"})
+				continue
+			}
+			commands = [][]string{append([]string{"gofmt", "-l", "-w"}, goFiles...)}
+			display[0] = fmt.Sprintf("gofmt -l -w <%d generated Go files>", len(goFiles))
+		case HookGit:
+			if reason := gitSkipReason(projectDir); reason != "" {
+				results = append(results, HookResult{Hook: hook, Skipped: true, Reason: reason})
+				continue
+			}
+			staged := stagedPaths(projectDir, paths, ranTidy)
+			if len(staged) == 0 {
+				results = append(results, HookResult{Hook: hook, Skipped: true, Reason: "no generated files to commit"})
+				continue
+			}
+			commands = [][]string{
+				{"git", "init"},
+				append([]string{"git", "add", "--"}, staged...),
+				{"git", "commit", "-m", "Initial commit from " + vars.AppName + " " + vars.AppVersion},
+			}
+			display[1] = fmt.Sprintf("git add -- <%d generated files>", len(staged))
+		default:
+			return results, fmt.Errorf("unknown hook %q", hook)
+		}
+
+		result := HookResult{Hook: hook}
+		for i, command := range commands {
+			line := strings.Join(command, " ")
+			if short, ok := display[i]; ok {
+				line = short
+			}
+			result.Commands = append(result.Commands, line)
+			out, err := runner.Run(ctx, projectDir, command[0], command[1:]...)
+			if err != nil {
+				if output := strings.TrimSpace(string(out)); output != "" {
+					return results, fmt.Errorf("hook %s: %s: %w\n%s", hook, line, err, output)
+				}
+				return results, fmt.Errorf("hook %s: %s: %w", hook, line, err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// stagedPaths returns the paths the git hook adds: paths, plus the go.sum
+// written by go mod tidy when the tidy hook ran.
+// generatedGoFiles returns the .go files of the slash-separated paths as
+// operating system paths.
+func generatedGoFiles(paths []string) []string {
+	var goFiles []string
+	for _, p := range paths {
+		if strings.HasSuffix(p, ".go") {
+			goFiles = append(goFiles, filepath.FromSlash(p))
+		}
+	}
+	return goFiles
+}
+
+func stagedPaths(projectDir string, paths []string, ranTidy bool) []string {
+	staged := append([]string(nil), paths...)
+	if !ranTidy {
+		return staged
+	}
+	for _, p := range paths {
+		if p == "go.sum" {
+			return staged
+		}
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "go.sum")); err == nil {
+		staged = append(staged, "go.sum")
+	}
+	return staged
+}
+
+// gitSkipReason explains why the git hook does not run in projectDir, or
+// returns "" when projectDir is not part of a repository yet. A generated
+// project inside a monorepo is committed with the rest of the repository.
+// Like git, the search for an enclosing repository stops below the
+// directories listed in GIT_CEILING_DIRECTORIES.
+func gitSkipReason(projectDir string) string {
+	if _, err := os.Stat(filepath.Join(projectDir, ".git")); err == nil {
+		return ".git already exists"
+	}
+	absDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return ""
+	}
+	ceilings := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("GIT_CEILING_DIRECTORIES")) {
+		if filepath.IsAbs(dir) {
+			ceilings[filepath.Clean(dir)] = true
+		}
+	}
+	for current := filepath.Dir(absDir); ; current = filepath.Dir(current) {
+		if ceilings[current] {
+			return ""
+		}
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return "inside git repository " + current
+		}
+		if filepath.Dir(current) == current {
+			return ""
+		}
+	}
+}
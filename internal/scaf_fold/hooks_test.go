@@ -0,0 +1,124 @@
+package scaf_fold
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SisyphusSQ/go-web-starter/vars"
+)
+
+type fakeRunnerForTest struct {
+	commands []string
+	fail     string
+}
+
+func (r *fakeRunnerForTest) Run(_ context.Context, _ string, name string, args ...string) ([]byte, error) {
+	line := strings.Join(append([]string{name}, args...), " ")
+	r.commands = append(r.commands, line)
+	if line == r.fail {
+		return []byte("fatal: unable to auto-detect email address\n"), errors.New("exit status 128")
+	}
+	return nil, nil
+}
+
+func TestParseHooksFlag(t *testing.T) {
+	got, err := ParseHooksFlag(" git, TIDY ,git")
+	if err != nil {
+		t.Fatalf("ParseHooksFlag() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{HookTidy, HookGit}) {
+		t.Fatalf("ParseHooksFlag() = %q, want run order", got)
+	}
+	if got, err := ParseHooksFlag(""); err != nil || len(got) != 0 {
+		t.Fatalf("ParseHooksFlag(\"\") = %q, %v", got, err)
+	}
+	if _, err := ParseHooksFlag("tidy,vet"); err == nil || !strings.Contains(err.Error(), `invalid hook "vet"`) {
+		t.Fatalf("ParseHooksFlag(tidy,vet) error = %v", err)
+	}
+}
+
+// isolateGitForTest keeps the git hook from finding a repository above dir,
+// such as one enclosing the temporary directory of the host.
+func isolateGitForTest(t *testing.T, dir string) {
+	t.Helper()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+}
+
+func TestDefaultHooks(t *testing.T) {
+	if got := DefaultHooks(); len(got) != 0 {
+		t.Fatalf("DefaultHooks() = %q, want none", got)
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	projectDir := t.TempDir()
+	isolateGitForTest(t, projectDir)
+	writeFileForTest(t, filepath.Join(projectDir, "go.sum"), "")
+	runner := &fakeRunnerForTest{}
+	results, err := RunHooks(context.Background(), projectDir, []string{HookTidy, HookFmt, HookGit}, []string{"go.mod", "main.go"}, runner)
+	if err != nil {
+		t.Fatalf("RunHooks() error = %v", err)
+	}
+	if len(results) != 3 || results[1].Commands[0] != "gofmt -l -w <1 generated Go files>" ||
+		results[2].Hook != HookGit || results[2].Commands[1] != "git add -- <3 generated files>" {
+		t.Fatalf("RunHooks() results = %#v", results)
+	}
+	want := []string{
+		"go mod tidy",
+		"gofmt -l -w main.go",
+		"git init",
+		"git add -- go.mod main.go go.sum",
+		"git commit -m Initial commit from " + vars.AppName + " " + vars.AppVersion,
+	}
+	if !reflect.DeepEqual(runner.commands, want) {
+		t.Fatalf("RunHooks() commands = %q, want %q", runner.commands, want)
+	}
+
+	// go.sum is only committed when tidy wrote it, and fmt leaves the files
+	// that were not generated alone.
+	runner = &fakeRunnerForTest{}
+	results, err = RunHooks(context.Background(), projectDir, []string{HookFmt, HookGit}, nil, runner)
+	if err != nil || len(runner.commands) != 0 || results[0].Reason != "no generated Go files" ||
+		results[1].Reason != "no generated files to commit" {
+		t.Fatalf("RunHooks() without generated files = %#v, %v, commands %q", results, err, runner.commands)
+	}
+
+	if err := os.Mkdir(filepath.Join(projectDir, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir .git: %v", err)
+	}
+	runner = &fakeRunnerForTest{}
+	results, err = RunHooks(context.Background(), projectDir, []string{HookGit}, []string{"go.mod"}, runner)
+	if err != nil || len(runner.commands) != 0 || !results[0].Skipped {
+		t.Fatalf("RunHooks() with .git = %#v, %v, commands %q", results, err, runner.commands)
+	}
+
+	nestedDir := filepath.Join(projectDir, "services", "billing")
+	if err := os.MkdirAll(nestedDir, 0o755); err != nil {
+		t.Fatalf("mkdir nested project: %v", err)
+	}
+	runner = &fakeRunnerForTest{}
+	results, err = RunHooks(context.Background(), nestedDir, []string{HookGit}, []string{"go.mod"}, runner)
+	if err != nil || len(runner.commands) != 0 || !results[0].Skipped ||
+		results[0].Reason != "inside git repository "+projectDir {
+		t.Fatalf("RunHooks() inside a repository = %#v, %v, commands %q", results, err, runner.commands)
+	}
+}
+
+func TestRunHooksStopsAtFailure(t *testing.T) {
+	projectDir := t.TempDir()
+	isolateGitForTest(t, projectDir)
+	runner := &fakeRunnerForTest{fail: "git add -- go.mod"}
+	results, err := RunHooks(context.Background(), projectDir, []string{HookGit, HookFmt}, []string{"go.mod"}, runner)
+	if err == nil || !strings.Contains(err.Error(), "hook git: git add -- <1 generated files>: exit status 128") ||
+		!strings.Contains(err.Error(), "unable to auto-detect email address") {
+		t.Fatalf("RunHooks() error = %v", err)
+	}
+	if len(results) != 0 || !reflect.DeepEqual(runner.commands, []string{"git init", "git add -- go.mod"}) {
+		t.Fatalf("RunHooks() results = %#v, commands %q", results, runner.commands)
+	}
+}
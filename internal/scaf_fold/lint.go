@@ -0,0 +1,249 @@
+package scaf_fold
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// dbSelections lists every valid --db selection.
+var dbSelections = []Databases{
+	{MySQL: true},
+	{Postgres: true},
+	{SQLite: true},
+	{MongoDB: true},
+	{MySQL: true, MongoDB: true},
+	{Postgres: true, MongoDB: true},
+	{SQLite: true, MongoDB: true},
+}
+
+// templateCombinations returns base with every --db selection combined with
+// every subset of the optional components.
+func templateCombinations(base TemplateData) []TemplateData {
+	all := Components()
+	var combos []TemplateData
+	for _, dbs := range dbSelections {
+		for mask := 0; mask < 1<<len(all); mask++ {
+			components := []string{}
+			for i, component := range all {
+				if mask&(1<<i) != 0 {
+					components = append(components, component.Name)
+				}
+			}
+			data := base
+			data.MySQL, data.Postgres, data.SQLite, data.MongoDB = dbs.MySQL, dbs.Postgres, dbs.SQLite, dbs.MongoDB
+			data.Components = components
+			combos = append(combos, data)
+		}
+	}
+	return combos
+}
+
+// LintProblem is a problem found in the template file at Path, or in the
+// tree as a whole when Path is empty. Combination names the first template
+// data that shows it, such as "db=mysql components=cron,redis".
+type LintProblem struct {
+	Path        string
+	Message     string
+	Combination string
+}
+
+// LintReport is the result of LintTemplates.
+type LintReport struct {
+	Files        int
+	Combinations int
+	Problems     []LintProblem
+}
+
+// LintTemplates checks the templates selected by opts for template authors.
+// Every .tmpl file is parsed, then the whole tree is rendered for every --db
+// and component combination, reporting unknown fields and functions, files
+// that no combination generates, and rendered .go files that fail the Go
+// syntax or type checks. Templates referencing .Extra keys are rendered with
+// placeholder values.
+func LintTemplates(opts Options) (LintReport, error) {
+	set, err := loadTemplateSet(opts)
+	if err != nil {
+		return LintReport{}, err
+	}
+
+	l := &templateLinter{
+		set:    set,
+		extra:  make(map[string]string),
+		seen:   make(map[string]bool),
+		parsed: make(map[string]bool),
+	}
+	for _, file := range set.files {
+		if l.isTemplate(file) {
+			l.parse(file)
+		}
+	}
+
+	combos := templateCombinations(TemplateData{
+		ModuleName:  "example.com/lint",
+		BinaryName:  "lint",
+		ProjectName: "lint",
+		GoVersion:   fallbackGoVersion,
+	})
+	reached := make(map[string]bool)
+	for _, data := range combos {
+		l.renderCombination(data, reached)
+	}
+	for _, file := range set.files {
+		if !reached[file.path] {
+			l.add(file.displayPath(), "not generated by any --db and component combination; check its manifest rule", "")
+		}
+	}
+
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		return l.problems[i].Path < l.problems[j].Path
+	})
+	return LintReport{Files: len(set.files), Combinations: len(combos), Problems: l.problems}, nil
+}
+
+type templateLinter struct {
+	set   templateSet
+	extra map[string]string
+	// seen deduplicates problems reported by several combinations.
+	seen map[string]bool
+	// parsed records the templates that parse; the others are not rendered.
+	parsed   map[string]bool
+	problems []LintProblem
+}
+
+func (l *templateLinter) add(path, message, combination string) {
+	key := path + "\x00" + message
+	if l.seen[key] {
+		return
+	}
+	l.seen[key] = true
+	l.problems = append(l.problems, LintProblem{Path: path, Message: message, Combination: combination})
+}
+
+func (l *templateLinter) isTemplate(file templateFile) bool {
+	if !strings.HasSuffix(file.path, ".tmpl") {
+		return false
+	}
+	rule, _ := l.set.manifest.match(file.path)
+	return !rule.Raw
+}
+
+func (l *templateLinter) parse(file templateFile) {
+	raw, err := fs.ReadFile(file.src.fsys, file.path)
+	if err != nil {
+		l.add(file.displayPath(), err.Error(), "")
+		return
+	}
+	if _, err := template.New(file.displayPath()).Funcs(templateFuncs()).Parse(string(raw)); err != nil {
+		l.add(file.displayPath(), err.Error(), "")
+		return
+	}
+	l.parsed[file.path] = true
+}
+
+// renderCombination renders every template for data, recording the files
+// it generates in reached, and type-checks the result when every Go file
+// rendered.
+func (l *templateLinter) renderCombination(data TemplateData, reached map[string]bool) {
+	combination := lintCombinationName(data)
+	var entries []renderedEntry
+	checkedGo := make(map[string]string)
+	failed := false
+	for _, file := range l.set.files {
+		include, err := l.set.manifest.includes(file.path, data)
+		if err != nil {
+			l.add(file.displayPath(), err.Error(), combination)
+			failed = true
+			continue
+		}
+		if !include {
+			continue
+		}
+		reached[file.path] = true
+
+		data.Extra = l.extra
+		outRelPath, err := l.renderWithPlaceholders(func() (string, error) {
+			return renderPath(file.displayPath(), strings.TrimSuffix(file.path, ".tmpl"), data)
+		})
+		if err != nil {
+			l.add(file.displayPath(), err.Error(), combination)
+			failed = true
+			continue
+		}
+		if !l.isTemplate(file) {
+			continue
+		}
+		isGo := strings.HasSuffix(outRelPath, ".go")
+		if !l.parsed[file.path] {
+			failed = failed || isGo
+			continue
+		}
+
+		raw, err := fs.ReadFile(file.src.fsys, file.path)
+		if err != nil {
+			l.add(file.displayPath(), err.Error(), combination)
+			failed = true
+			continue
+		}
+		var content []byte
+		_, err = l.renderWithPlaceholders(func() (string, error) {
+			rendered, err := renderTemplate(file.displayPath(), raw, data)
+			content = rendered
+			return "", err
+		})
+		if err == nil && isGo {
+			content, err = formatGoSource(file.displayPath(), outRelPath, content)
+			checkedGo[outRelPath] = file.displayPath()
+		}
+		if err != nil {
+			l.add(file.displayPath(), err.Error(), combination)
+			failed = failed || isGo
+			continue
+		}
+		entries = append(entries, renderedEntry{path: outRelPath, content: content})
+	}
+
+	if failed {
+		return
+	}
+	problems, err := goTypeProblems(data.ModuleName, entries, checkedGo)
+	if err != nil {
+		l.add("", err.Error(), combination)
+		return
+	}
+	for _, problem := range problems {
+		l.add(problem.template, fmt.Sprintf(
+			"%s:%d:%d: %s",
+			problem.pos.Filename,
+			problem.pos.Line,
+			problem.pos.Column,
+			problem.msg,
+		), combination)
+	}
+}
+
+// renderWithPlaceholders calls render, giving each .Extra key it reports as
+// missing a placeholder value and retrying.
+func (l *templateLinter) renderWithPlaceholders(render func() (string, error)) (string, error) {
+	for {
+		out, err := render()
+		if err == nil {
+			return out, nil
+		}
+		m := missingExtraPattern.FindStringSubmatch(err.Error())
+		if m == nil || l.extra[m[1]] != "" {
+			return "", err
+		}
+		l.extra[m[1]] = "lint-" + m[1]
+	}
+}
+
+func lintCombinationName(data TemplateData) string {
+	components := "none"
+	if len(data.Components) > 0 {
+		components = strings.Join(data.Components, ",")
+	}
+	return fmt.Sprintf("db=%s components=%s", strings.Join(data.databaseNames(), ","), components)
+}
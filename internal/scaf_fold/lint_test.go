@@ -0,0 +1,68 @@
+package scaf_fold
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintTemplates(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"go.mod.tmpl":               "module {{ .ModuleName }}\n",
+		"OWNERS.tmpl":               "{{ .Extra.team }}\n",
+		"README.md.tmpl":            "# {{ .ProjectName | shout }}\n",
+		"config.yml.tmpl":           "name: {{ .Name }}\n",
+		"internal/db/db.go.tmpl":    "package db\n\nfunc Open() {\n{{- if .Postgres }}\n\tif true {\n{{- end }}\n}\n",
+		"internal/db/mongo.go.tmpl": "package db\n\nimport \"os\"\n",
+		"legacy.txt.tmpl":           "unused\n",
+		"static/logo.txt":           "{{ not a template }}\n",
+		"manifest.yaml": "rules:\n" +
+			"  - path: legacy.txt.tmpl\n    when: and .MySQL .Postgres\n" +
+			"  - path: internal/db/mongo.go.tmpl\n    when: .MongoDB\n",
+	})
+
+	report, err := LintTemplates(Options{TemplateDir: templateDir})
+	if err != nil {
+		t.Fatalf("LintTemplates() error = %v", err)
+	}
+	if report.Files != 8 || report.Combinations != 224 {
+		t.Fatalf("LintTemplates() checked %d files across %d combinations", report.Files, report.Combinations)
+	}
+
+	want := map[string]string{
+		"README.md.tmpl":            `function "shout" not defined`,
+		"config.yml.tmpl":           "can't evaluate field Name in type scaf_fold.TemplateData",
+		"internal/db/db.go.tmpl":    "renders invalid Go: internal/db/db.go:",
+		"internal/db/mongo.go.tmpl": `internal/db/mongo.go:3:8: "os" imported and not used`,
+		"legacy.txt.tmpl":           "not generated by any --db and component combination",
+	}
+	if len(report.Problems) != len(want) {
+		t.Fatalf("LintTemplates() problems = %#v, want %d", report.Problems, len(want))
+	}
+	for _, problem := range report.Problems {
+		var matched bool
+		for path, message := range want {
+			if strings.HasSuffix(problem.Path, path) && strings.Contains(problem.Message, message) {
+				matched = true
+			}
+		}
+		if !matched {
+			t.Errorf("unexpected problem %#v", problem)
+		}
+	}
+}
+
+func TestLintTemplatesReportsFirstCombination(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"app.go.tmpl": "package app\n{{ if .Has \"jwt\" }}func{{ end }}\n",
+	})
+
+	report, err := LintTemplates(Options{TemplateDir: templateDir})
+	if err != nil {
+		t.Fatalf("LintTemplates() error = %v", err)
+	}
+	if len(report.Problems) != 1 || report.Problems[0].Combination != "db=mysql components=jwt" {
+		t.Fatalf("LintTemplates() problems = %#v", report.Problems)
+	}
+}
@@ -0,0 +1,216 @@
+package scaf_fold
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/SisyphusSQ/go-web-starter/vars"
+)
+
+// LockfileName is the file Generate writes at the project root to record how
+// the project was generated.
+const LockfileName = ".go-web-starter.json"
+
+// Lockfile records the starter version and template data a project was
+// generated from, and the content hash of every rendered file, so that later
+// tooling can tell pristine files from locally modified ones.
+type Lockfile struct {
+	StarterVersion string       `json:"starterVersion"`
+	Data           TemplateData `json:"data"`
+	// TemplateDir and Overlays are the --template-dir and --overlay
+	// directories the project was rendered from, relative to the project
+	// root. Diff and upgrade render with them unless told otherwise.
+	TemplateDir string   `json:"templateDir,omitempty"`
+	Overlays    []string `json:"overlays,omitempty"`
+	// Files maps slash-separated paths relative to the project root to the
+	// hash of the content Generate wrote, as returned by HashContent.
+	Files map[string]string `json:"files"`
+	// Origins maps the files rendered from TemplateDir or an overlay to that
+	// directory; other files come from the built-in templates.
+	Origins map[string]string `json:"origins,omitempty"`
+}
+
+// HashContent returns the lockfile hash of a file's content.
+func HashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ReadLockfile reads the lockfile at the root of projectDir.
+func ReadLockfile(projectDir string) (Lockfile, error) {
+	path := filepath.Join(projectDir, LockfileName)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Lockfile{}, fmt.Errorf("read lockfile: %w", err)
+	}
+
+	var lock Lockfile
+	if err := json.Unmarshal(raw, &lock); err != nil {
+		return Lockfile{}, fmt.Errorf("parse lockfile %s: %w", path, err)
+	}
+	if lock.Files == nil {
+		lock.Files = make(map[string]string)
+	}
+	return lock, nil
+}
+
+// Pristine reports whether the file at relPath still has the content
+// Generate wrote. Files missing from the lockfile or from disk are not
+// pristine.
+func (l Lockfile) Pristine(projectDir, relPath string) (bool, error) {
+	want, ok := l.Files[relPath]
+	if !ok {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(relPath)))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read %s: %w", relPath, err)
+	}
+	return HashContent(content) == want, nil
+}
+
+// newLockfile builds the lockfile of a render into projectDir. The component
+// selection is recorded explicitly so that a later change of the defaults
+// does not change what the project is re-rendered with.
+func newLockfile(projectDir string, data TemplateData, opts Options, entries []renderedEntry) Lockfile {
+	components := make([]string, 0, len(data.enabledComponents()))
+	data.Components = append(components, data.enabledComponents()...)
+
+	lock := Lockfile{
+		StarterVersion: vars.AppVersion,
+		Data:           data,
+		Files:          make(map[string]string),
+	}
+	recorded := make(map[string]string)
+	record := func(dir string) string {
+		if _, ok := recorded[dir]; !ok {
+			recorded[dir] = recordedDir(projectDir, dir)
+		}
+		return recorded[dir]
+	}
+	if opts.TemplateDir != "" {
+		lock.TemplateDir = record(opts.TemplateDir)
+	}
+	for _, overlayDir := range opts.Overlays {
+		if overlayDir != "" {
+			lock.Overlays = append(lock.Overlays, record(overlayDir))
+		}
+	}
+	for _, entry := range entries {
+		if entry.isDir {
+			continue
+		}
+		lock.Files[entry.path] = HashContent(entry.content)
+		if entry.origin != "" {
+			if lock.Origins == nil {
+				lock.Origins = make(map[string]string)
+			}
+			lock.Origins[entry.path] = record(entry.origin)
+		}
+	}
+	return lock
+}
+
+// recordedDir returns dir as recorded in the lockfile of projectDir:
+// slash-separated and relative to the project, so that the project can move
+// together with its templates, or absolute when projectDir is unknown.
+func recordedDir(projectDir, dir string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.ToSlash(dir)
+	}
+	if projectDir == "" {
+		return filepath.ToSlash(absDir)
+	}
+	absProject, err := filepath.Abs(projectDir)
+	if err != nil {
+		return filepath.ToSlash(absDir)
+	}
+	rel, err := filepath.Rel(absProject, absDir)
+	if err != nil {
+		return filepath.ToSlash(absDir)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// resolveRecordedDir turns a directory recorded by recordedDir back into a
+// path usable from the current directory.
+func resolveRecordedDir(projectDir, dir string) string {
+	if dir == "" {
+		return ""
+	}
+	path := filepath.FromSlash(dir)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(projectDir, path)
+	}
+	return path
+}
+
+// templateOptions returns opts with the template directory and overlays
+// recorded in the lockfile when opts names none itself.
+func (l Lockfile) templateOptions(projectDir string, opts Options) Options {
+	if opts.TemplateDir != "" || len(opts.Overlays) > 0 {
+		return opts
+	}
+	opts.TemplateDir = resolveRecordedDir(projectDir, l.TemplateDir)
+	opts.Overlays = nil
+	for _, overlayDir := range l.Overlays {
+		opts.Overlays = append(opts.Overlays, resolveRecordedDir(projectDir, overlayDir))
+	}
+	return opts
+}
+
+// origin returns the absolute template directory relPath was rendered
+// from, "" for the built-in templates.
+func (l Lockfile) origin(projectDir, relPath string) string {
+	return sameDirKey(resolveRecordedDir(projectDir, l.Origins[relPath]))
+}
+
+// renderedLayers returns the template directories a render with opts reads,
+// keyed like Lockfile.origin.
+func renderedLayers(opts Options) map[string]bool {
+	layers := map[string]bool{sameDirKey(opts.TemplateDir): true}
+	for _, overlayDir := range opts.Overlays {
+		if overlayDir != "" {
+			layers[sameDirKey(overlayDir)] = true
+		}
+	}
+	return layers
+}
+
+func sameDirKey(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	if absDir, err := filepath.Abs(dir); err == nil {
+		return absDir
+	}
+	return filepath.Clean(dir)
+}
+
+// withLockfile appends the lockfile of entries, rendered with opts into
+// projectDir, to them.
+func withLockfile(projectDir string, data TemplateData, opts Options, entries []renderedEntry) ([]renderedEntry, error) {
+	content, err := encodeLockfile(newLockfile(projectDir, data, opts, entries))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(entries, renderedEntry{path: LockfileName, content: content}), nil
+}
+
+func encodeLockfile(lock Lockfile) ([]byte, error) {
+	content, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode lockfile: %w", err)
+	}
+	return append(content, '\n'), nil
+}
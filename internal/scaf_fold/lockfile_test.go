@@ -0,0 +1,145 @@
+package scaf_fold
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SisyphusSQ/go-web-starter/vars"
+)
+
+func TestGenerateWritesLockfile(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "lock-web")
+	data := TemplateData{
+		ModuleName:  "github.com/test/lock-web",
+		BinaryName:  "lock-web",
+		ProjectName: "lock-web",
+		Postgres:    true,
+		Components:  []string{"jwt"},
+	}
+	if err := Generate(outputDir, data); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	lock, err := ReadLockfile(outputDir)
+	if err != nil {
+		t.Fatalf("ReadLockfile() error = %v", err)
+	}
+	if lock.StarterVersion != vars.AppVersion {
+		t.Fatalf("StarterVersion = %q, want %q", lock.StarterVersion, vars.AppVersion)
+	}
+
+	data.GoVersion = defaultGoVersion()
+	if !reflect.DeepEqual(lock.Data, data) {
+		t.Fatalf("Data = %#v, want %#v", lock.Data, data)
+	}
+
+	if _, ok := lock.Files[LockfileName]; ok {
+		t.Fatalf("lockfile should not record its own hash")
+	}
+	var files int
+	err = filepath.WalkDir(outputDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(outputDir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == LockfileName {
+			return nil
+		}
+		files++
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if got, want := lock.Files[relPath], HashContent(content); got != want {
+			t.Errorf("lockfile hash of %s = %q, want %q", relPath, got, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk generated project: %v", err)
+	}
+	if files != len(lock.Files) {
+		t.Fatalf("lockfile records %d files, generated %d", len(lock.Files), files)
+	}
+}
+
+func TestLockfileRecordsDefaultComponents(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "lock-web")
+	if err := Generate(outputDir, TemplateData{
+		ModuleName:  "github.com/test/lock-web",
+		BinaryName:  "lock-web",
+		ProjectName: "lock-web",
+		MongoDB:     true,
+	}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	lock, err := ReadLockfile(outputDir)
+	if err != nil {
+		t.Fatalf("ReadLockfile() error = %v", err)
+	}
+	if !reflect.DeepEqual(lock.Data.Components, DefaultComponents()) {
+		t.Fatalf("Components = %v, want the defaults %v", lock.Data.Components, DefaultComponents())
+	}
+
+	raw := readFileForAssertion(t, filepath.Join(outputDir, LockfileName))
+	if strings.Contains(raw, `"components": null`) {
+		t.Fatalf("lockfile should record the resolved components:\n%s", raw)
+	}
+}
+
+func TestLockfilePristine(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "lock-web")
+	if err := Generate(outputDir, TemplateData{
+		ModuleName:  "github.com/test/lock-web",
+		BinaryName:  "lock-web",
+		ProjectName: "lock-web",
+		MySQL:       true,
+	}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	lock, err := ReadLockfile(outputDir)
+	if err != nil {
+		t.Fatalf("ReadLockfile() error = %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(outputDir, "Makefile"), []byte("all:\n"), 0o644); err != nil {
+		t.Fatalf("modify Makefile: %v", err)
+	}
+	if err := os.Remove(filepath.Join(outputDir, "Dockerfile")); err != nil {
+		t.Fatalf("remove Dockerfile: %v", err)
+	}
+
+	for relPath, want := range map[string]bool{
+		"go.mod":       true,
+		"Makefile":     false,
+		"Dockerfile":   false,
+		"untracked.go": false,
+	} {
+		got, err := lock.Pristine(outputDir, relPath)
+		if err != nil {
+			t.Fatalf("Pristine(%s) error = %v", relPath, err)
+		}
+		if got != want {
+			t.Errorf("Pristine(%s) = %v, want %v", relPath, got, want)
+		}
+	}
+}
+
+func TestReadLockfileRejectsInvalidJSON(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, LockfileName), []byte("{"), 0o644); err != nil {
+		t.Fatalf("write lockfile: %v", err)
+	}
+	if _, err := ReadLockfile(dir); err == nil || !strings.Contains(err.Error(), "parse lockfile") {
+		t.Fatalf("ReadLockfile() error = %v, want parse error", err)
+	}
+}
@@ -0,0 +1,176 @@
+package scaf_fold
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestFile is the name of the inclusion rules file at the root of a
+// template tree. It is read by the walker and never rendered.
+const manifestFile = "manifest.yaml"
+
+// templateManifest decides which template files are rendered for a given
+// TemplateData, and how they are written. Files without a matching rule are
+// always rendered.
+type templateManifest struct {
+	rules []manifestRule
+}
+
+type manifestRule struct {
+	Path string `yaml:"path"`
+	When string `yaml:"when"`
+	// Raw copies the matching files byte-for-byte even when they have the
+	// .tmpl suffix, which is still stripped.
+	Raw bool `yaml:"raw"`
+	// Mode is the octal permission of the matching files, such as "0755".
+	// It overrides the permission taken from the template file.
+	Mode string `yaml:"mode"`
+
+	source string
+	cond   *template.Template
+	mode   fs.FileMode
+}
+
+type manifestDocument struct {
+	Rules []manifestRule `yaml:"rules"`
+}
+
+func parseManifest(source string, raw []byte) ([]manifestRule, error) {
+	var doc manifestDocument
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse manifest %s: %w", source, err)
+	}
+
+	rules := make([]manifestRule, 0, len(doc.Rules))
+	for i, rule := range doc.Rules {
+		rulePath := strings.TrimSpace(rule.Path)
+		if rulePath == "" {
+			return nil, fmt.Errorf("parse manifest %s: rule %d has an empty path", source, i+1)
+		}
+		rulePath = path.Clean(rulePath)
+		if path.IsAbs(rulePath) || rulePath == ".." || strings.HasPrefix(rulePath, "../") {
+			return nil, fmt.Errorf("parse manifest %s: rule path %q escapes the template root", source, rule.Path)
+		}
+
+		rule.Path = rulePath
+		rule.source = source
+		if modeStr := strings.TrimSpace(rule.Mode); modeStr != "" {
+			mode, err := strconv.ParseUint(modeStr, 8, 32)
+			if err != nil || mode > 0o777 {
+				return nil, fmt.Errorf("parse manifest %s: invalid mode %q for %s", source, rule.Mode, rulePath)
+			}
+			rule.mode = fs.FileMode(mode)
+		}
+		if when := strings.TrimSpace(rule.When); when != "" {
+			cond, err := template.New(rulePath).
+				Option("missingkey=error").
+				Funcs(templateFuncs()).
+				Parse("{{ if " + when + " }}true{{ end }}")
+			if err != nil {
+				return nil, fmt.Errorf(
+					"parse manifest %s: invalid when expression %q for %s: %w",
+					source,
+					rule.When,
+					rulePath,
+					err,
+				)
+			}
+			rule.cond = cond
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// add appends rules; a later rule for the same path replaces an earlier one.
+func (m *templateManifest) add(rules []manifestRule) {
+	m.rules = append(m.rules, rules...)
+}
+
+// covering returns the rules covering templatePath from the least to the
+// most specific, keeping only the last rule added for each path.
+func (m templateManifest) covering(templatePath string) []manifestRule {
+	var rules []manifestRule
+	byPath := make(map[string]int)
+	for _, rule := range m.rules {
+		if !ruleCovers(rule.Path, templatePath) {
+			continue
+		}
+		if i, ok := byPath[rule.Path]; ok {
+			rules[i] = rule
+			continue
+		}
+		byPath[rule.Path] = len(rules)
+		rules = append(rules, rule)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].Path) < len(rules[j].Path)
+	})
+	return rules
+}
+
+// match returns the most specific rule covering templatePath. The rule
+// inherits raw from any enclosing rule and, when it sets no mode, the mode of
+// the nearest enclosing rule that sets one.
+func (m templateManifest) match(templatePath string) (manifestRule, bool) {
+	rules := m.covering(templatePath)
+	if len(rules) == 0 {
+		return manifestRule{}, false
+	}
+	rule := rules[len(rules)-1]
+	for i := len(rules) - 2; i >= 0; i-- {
+		rule.Raw = rule.Raw || rules[i].Raw
+		if rule.mode == 0 && rules[i].mode != 0 {
+			rule.Mode, rule.mode = rules[i].Mode, rules[i].mode
+		}
+	}
+	return rule, true
+}
+
+// includes reports whether templatePath is rendered for data: the when
+// conditions of every rule covering it must hold, so a rule for a file
+// inside a conditional directory cannot generate it without the directory.
+func (m templateManifest) includes(templatePath string, data TemplateData) (bool, error) {
+	for _, rule := range m.covering(templatePath) {
+		ok, err := rule.eval(data)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (r manifestRule) eval(data TemplateData) (bool, error) {
+	if r.cond == nil {
+		return true, nil
+	}
+
+	var buf bytes.Buffer
+	if err := r.cond.Execute(&buf, data); err != nil {
+		return false, fmt.Errorf(
+			"evaluate manifest %s rule for %s (when: %s): %w",
+			r.source,
+			r.Path,
+			r.When,
+			err,
+		)
+	}
+	return buf.String() == "true", nil
+}
+
+func ruleCovers(rulePath, templatePath string) bool {
+	return rulePath == "." ||
+		rulePath == templatePath ||
+		strings.HasPrefix(templatePath, rulePath+"/")
+}
@@ -0,0 +1,207 @@
+package scaf_fold
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestManifestCoversEveryTemplate(t *testing.T) {
+	set, err := loadTemplateSet(Options{})
+	if err != nil {
+		t.Fatalf("loadTemplateSet() error = %v", err)
+	}
+	if len(set.manifest.rules) == 0 {
+		t.Fatalf("embedded templates have no %s rules", manifestFile)
+	}
+
+	used := make(map[string]bool)
+	for _, file := range set.files {
+		rule, ok := set.manifest.match(file.path)
+		if !ok {
+			t.Errorf("template %s is not covered by any %s rule", file.path, manifestFile)
+			continue
+		}
+		used[rule.Path] = true
+	}
+
+	for _, rule := range set.manifest.rules {
+		if !used[rule.Path] {
+			t.Errorf("%s rule %q does not match any template file", manifestFile, rule.Path)
+		}
+	}
+}
+
+func TestManifestCombinesEnclosingRules(t *testing.T) {
+	rules, err := parseManifest("manifest.yaml", []byte(`
+rules:
+  - path: internal/lib
+    when: .SQL
+  - path: internal/lib/gorm/schema.go.tmpl
+    when: .SQLite
+  - path: internal/lib/log/logger.go.tmpl
+  - path: internal/lib/mongodb
+    when: .MongoDB
+`))
+	if err != nil {
+		t.Fatalf("parseManifest() error = %v", err)
+	}
+	var manifest templateManifest
+	manifest.add(rules)
+
+	mongoOnly := TemplateData{MongoDB: true}
+	sqliteMongo := TemplateData{SQLite: true, MongoDB: true}
+	tests := []struct {
+		path string
+		data TemplateData
+		want bool
+	}{
+		{path: "internal/lib/gorm/gorm.go.tmpl", data: mongoOnly, want: false},
+		{path: "internal/lib/log/logger.go.tmpl", data: mongoOnly, want: false},
+		{path: "internal/lib/mongodb/mongodb.go.tmpl", data: mongoOnly, want: false},
+		{path: "internal/library.go.tmpl", data: mongoOnly, want: true},
+		{path: "internal/lib/gorm/schema.go.tmpl", data: TemplateData{MySQL: true}, want: false},
+		{path: "internal/lib/gorm/schema.go.tmpl", data: sqliteMongo, want: true},
+		{path: "internal/lib/log/logger.go.tmpl", data: sqliteMongo, want: true},
+		{path: "internal/lib/mongodb/mongodb.go.tmpl", data: sqliteMongo, want: true},
+	}
+	for _, tt := range tests {
+		got, err := manifest.includes(tt.path, tt.data)
+		if err != nil {
+			t.Fatalf("includes(%s) error = %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("includes(%s, %+v) = %v, want %v", tt.path, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestManifestModeRuleInsideConditionalDirectory(t *testing.T) {
+	rules, err := parseManifest("manifest.yaml", []byte(`
+rules:
+  - path: internal/cron
+    when: .Has "cron"
+    raw: true
+    mode: "0700"
+  - path: internal/cron/run.sh.tmpl
+    mode: "0755"
+`))
+	if err != nil {
+		t.Fatalf("parseManifest() error = %v", err)
+	}
+	var manifest templateManifest
+	manifest.add(rules)
+
+	for _, tt := range []struct {
+		components []string
+		want       bool
+	}{
+		{components: []string{}, want: false},
+		{components: []string{"cron"}, want: true},
+	} {
+		got, err := manifest.includes("internal/cron/run.sh.tmpl", TemplateData{Components: tt.components})
+		if err != nil {
+			t.Fatalf("includes() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("includes(internal/cron/run.sh.tmpl) with components %q = %v, want %v", tt.components, got, tt.want)
+		}
+	}
+
+	rule, ok := manifest.match("internal/cron/run.sh.tmpl")
+	if !ok || rule.Path != "internal/cron/run.sh.tmpl" || rule.mode != 0o755 || !rule.Raw {
+		t.Fatalf("match(run.sh.tmpl) = %+v, %v, want own mode and inherited raw", rule, ok)
+	}
+	if rule, _ := manifest.match("internal/cron/cron.go.tmpl"); rule.mode != 0o700 {
+		t.Fatalf("match(cron.go.tmpl) mode = %o, want 0700", rule.mode)
+	}
+}
+
+func TestManifestLaterRuleOverridesSamePath(t *testing.T) {
+	var manifest templateManifest
+	for _, raw := range []string{
+		"rules:\n  - path: internal/cron\n",
+		"rules:\n  - path: internal/cron\n    when: .MySQL\n",
+	} {
+		rules, err := parseManifest("manifest.yaml", []byte(raw))
+		if err != nil {
+			t.Fatalf("parseManifest() error = %v", err)
+		}
+		manifest.add(rules)
+	}
+
+	got, err := manifest.includes("internal/cron/cron.go.tmpl", TemplateData{MongoDB: true})
+	if err != nil {
+		t.Fatalf("includes() error = %v", err)
+	}
+	if got {
+		t.Fatal("later overlay rule should exclude internal/cron without MySQL")
+	}
+}
+
+func TestParseManifestRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{
+			name:    "bad expression",
+			raw:     "rules:\n  - path: app\n    when: \"{{\"\n",
+			wantErr: "invalid when expression",
+		},
+		{
+			name:    "empty path",
+			raw:     "rules:\n  - when: .MySQL\n",
+			wantErr: "empty path",
+		},
+		{
+			name:    "escaping path",
+			raw:     "rules:\n  - path: ../outside\n",
+			wantErr: "escapes the template root",
+		},
+		{
+			name:    "bad mode",
+			raw:     "rules:\n  - path: scripts\n    mode: \"rwx\"\n",
+			wantErr: `invalid mode "rwx"`,
+		},
+		{
+			name:    "mode out of range",
+			raw:     "rules:\n  - path: scripts\n    mode: \"4755\"\n",
+			wantErr: `invalid mode "4755"`,
+		},
+		{
+			name:    "unknown key",
+			raw:     "rules:\n  - path: app\n    if: .MySQL\n",
+			wantErr: "parse manifest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseManifest("manifest.yaml", []byte(tt.raw))
+			if err == nil {
+				t.Fatal("parseManifest() expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("parseManifest() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManifestReportsUnknownField(t *testing.T) {
+	rules, err := parseManifest("manifest.yaml", []byte("rules:\n  - path: app\n    when: .Unknown\n"))
+	if err != nil {
+		t.Fatalf("parseManifest() error = %v", err)
+	}
+	var manifest templateManifest
+	manifest.add(rules)
+
+	_, err = manifest.includes("app/main.go.tmpl", TemplateData{MySQL: true})
+	if err == nil {
+		t.Fatal("includes() expected error for unknown field, got nil")
+	}
+	if !strings.Contains(err.Error(), "rule for app") {
+		t.Fatalf("includes() error should name the rule: %v", err)
+	}
+}
@@ -0,0 +1,636 @@
+package scaf_fold
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+//go:embed all:_resource
+var resourceFS embed.FS
+
+const resourceRoot = "_resource"
+
+// ResourceSpec describes a CRUD resource added to an existing project by
+// AddResource.
+type ResourceSpec struct {
+	// Name is the singular snake_case resource name, such as "order_item".
+	Name   string
+	Fields []ResourceField
+	// DB is the SQL engine of the project: mysql, postgres or sqlite. Empty
+	// detects it from the GORM driver required in go.mod, or from the
+	// lockfile of a project generated with --no-module.
+	DB string
+}
+
+// ResourceField is a column of a resource, declared as name:type.
+type ResourceField struct {
+	Name string
+	Type string
+}
+
+// ResourceResult reports what AddResource wrote, relative to the project
+// directory.
+type ResourceResult struct {
+	DB      string
+	Created []string
+	Updated []string
+}
+
+type resourceFieldType struct {
+	goType   string
+	nullable bool
+	sqlTypes map[string]string
+}
+
+var resourceFieldTypes = map[string]resourceFieldType{
+	"string": {goType: "string", sqlTypes: map[string]string{
+		"mysql":    "varchar(255) NOT NULL DEFAULT ''",
+		"postgres": "varchar(255) NOT NULL DEFAULT ''",
+		"sqlite":   "text NOT NULL DEFAULT ''",
+	}},
+	"int": {goType: "int", sqlTypes: map[string]string{
+		"mysql":    "bigint NOT NULL DEFAULT 0",
+		"postgres": "bigint NOT NULL DEFAULT 0",
+		"sqlite":   "integer NOT NULL DEFAULT 0",
+	}},
+	"int32": {goType: "int32", sqlTypes: map[string]string{
+		"mysql":    "int NOT NULL DEFAULT 0",
+		"postgres": "integer NOT NULL DEFAULT 0",
+		"sqlite":   "integer NOT NULL DEFAULT 0",
+	}},
+	"int64": {goType: "int64", sqlTypes: map[string]string{
+		"mysql":    "bigint NOT NULL DEFAULT 0",
+		"postgres": "bigint NOT NULL DEFAULT 0",
+		"sqlite":   "integer NOT NULL DEFAULT 0",
+	}},
+	"float64": {goType: "float64", sqlTypes: map[string]string{
+		"mysql":    "double NOT NULL DEFAULT 0",
+		"postgres": "double precision NOT NULL DEFAULT 0",
+		"sqlite":   "real NOT NULL DEFAULT 0",
+	}},
+	"bool": {goType: "bool", sqlTypes: map[string]string{
+		"mysql":    "tinyint(1) NOT NULL DEFAULT 0",
+		"postgres": "boolean NOT NULL DEFAULT false",
+		"sqlite":   "integer NOT NULL DEFAULT 0",
+	}},
+	"time": {goType: "*time.Time", nullable: true, sqlTypes: map[string]string{
+		"mysql":    "datetime NULL DEFAULT NULL",
+		"postgres": "timestamptz NULL",
+		"sqlite":   "datetime NULL",
+	}},
+}
+
+const resourceFieldTypeNames = "string,int,int32,int64,float64,bool,time"
+
+// sqlDriverModules maps each SQL engine to the GORM driver that go.mod.tmpl
+// requires for it.
+var sqlDriverModules = []struct {
+	engine string
+	module string
+}{
+	{engine: "mysql", module: "gorm.io/driver/mysql"},
+	{engine: "postgres", module: "gorm.io/driver/postgres"},
+	{engine: "sqlite", module: "github.com/glebarez/sqlite"},
+}
+
+var (
+	resourceNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	// reservedResourceIdents are identifiers used by the resource templates,
+	// which the resource variable names must not shadow.
+	reservedResourceIdents = map[string]bool{
+		"base_vo": true, "c": true, "cancel": true, "conditions": true, "context": true,
+		"controller": true, "ctx": true, "do": true, "e": true, "echo": true,
+		"engine": true, "err": true, "errors": true, "g": true, "gormv2": true,
+		"h": true, "id": true, "log": true, "m": true, "page": true,
+		"pageSize": true, "repo": true, "req": true, "resp": true, "s": true,
+		"start": true, "strconv": true, "time": true, "timeout": true, "updates": true,
+		"utils": true, "vo": true,
+	}
+	reservedResourceColumns = map[string]bool{"id": true, "created_at": true, "updated_at": true}
+)
+
+// resourceData is the template data of the _resource templates.
+type resourceData struct {
+	ModuleName string
+	Engine     string
+	Name       string
+	Type       string
+	Var        string
+	VarPlural  string
+	Table      string
+	Route      string
+	HasTime    bool
+	Fields     []resourceFieldData
+}
+
+type resourceFieldData struct {
+	Column     string
+	GoName     string
+	GoType     string
+	UpdateType string
+	SQLType    string
+	Nullable   bool
+}
+
+// resourceFiles lists the rendered files of a resource and where they go,
+// given the resource name and table.
+var resourceFiles = []struct {
+	template string
+	output   func(d resourceData) string
+}{
+	{"do.go.tmpl", func(d resourceData) string { return "internal/models/do/mysql/example_do/" + d.Name + ".go" }},
+	{"vo.go.tmpl", func(d resourceData) string { return "internal/models/vo/" + d.Name + ".go" }},
+	{"repository.go.tmpl", func(d resourceData) string { return "internal/repository/mysql/example_repo/" + d.Name + ".go" }},
+	{"service.go.tmpl", func(d resourceData) string { return "internal/service/example_srv/" + d.Name + "_service.go" }},
+	{"handler.go.tmpl", func(d resourceData) string { return "internal/controller/example_controller/" + d.Name + "_handler.go" }},
+	{"schema.sql.tmpl", func(d resourceData) string { return "docs/schema/" + d.Table + ".sql" }},
+}
+
+// resourceProviders lists the fx registrations added for a resource.
+var resourceProviders = []struct {
+	file       string
+	importPath string
+	funcName   func(d resourceData) string
+}{
+	{"internal/repository/module.go", "internal/repository/mysql/example_repo", func(d resourceData) string { return "New" + d.Type + "Repository" }},
+	{"internal/service/module.go", "internal/service/example_srv", func(d resourceData) string { return "New" + d.Type + "Service" }},
+	{"internal/controller/module.go", "internal/controller/example_controller", func(d resourceData) string { return "Init" + d.Type + "Controller" }},
+}
+
+const sqliteSchemaEmbedFile = "docs/schema/schema.go"
+
+// ParseResourceFields parses a --fields value such as
+// "name:string,amount:int64".
+func ParseResourceFields(val string) ([]ResourceField, error) {
+	var fields []ResourceField
+	for _, rawToken := range strings.Split(val, ",") {
+		token := strings.TrimSpace(rawToken)
+		if token == "" {
+			continue
+		}
+
+		name, typ, ok := strings.Cut(token, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid field %q: expected name:type", rawToken)
+		}
+		fields = append(fields, ResourceField{
+			Name: strings.TrimSpace(name),
+			Type: strings.ToLower(strings.TrimSpace(typ)),
+		})
+	}
+
+	if err := validateResourceFields(fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func validateResourceFields(fields []ResourceField) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("at least one field is required, e.g. name:string")
+	}
+
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if !resourceNamePattern.MatchString(field.Name) {
+			return fmt.Errorf("invalid field name %q: use lower snake_case, e.g. unit_price", field.Name)
+		}
+		if reservedResourceColumns[field.Name] {
+			return fmt.Errorf("field %q is generated for every resource and cannot be declared", field.Name)
+		}
+		if seen[field.Name] {
+			return fmt.Errorf("duplicate field %q", field.Name)
+		}
+		seen[field.Name] = true
+		if _, ok := resourceFieldTypes[field.Type]; !ok {
+			return fmt.Errorf(
+				"invalid type %q for field %q: allowed types are %s",
+				field.Type,
+				field.Name,
+				resourceFieldTypeNames,
+			)
+		}
+	}
+	return nil
+}
+
+func validateResourceName(name string) error {
+	if !resourceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid resource name %q: use singular lower snake_case, e.g. order_item", name)
+	}
+	if variable := lowerCamel(name); reservedResourceIdents[variable] || token.IsKeyword(variable) {
+		return fmt.Errorf("resource name %q clashes with an identifier used by the generated code", name)
+	}
+	return nil
+}
+
+// AddResource renders the CRUD layers of a resource into the project at
+// projectDir and registers its providers in the repository, service and
+// controller modules. It refuses to overwrite existing files, and restores
+// the project when a write fails.
+func AddResource(projectDir string, spec ResourceSpec) (ResourceResult, error) {
+	if err := validateResourceName(spec.Name); err != nil {
+		return ResourceResult{}, err
+	}
+	if err := validateResourceFields(spec.Fields); err != nil {
+		return ResourceResult{}, err
+	}
+
+	modulePath, engine, err := readProjectModule(projectDir, spec.DB)
+	if err != nil {
+		return ResourceResult{}, err
+	}
+	data := newResourceData(modulePath, engine, spec)
+
+	created, err := renderResource(projectDir, data)
+	if err != nil {
+		return ResourceResult{}, err
+	}
+	updated, err := registerResource(projectDir, data)
+	if err != nil {
+		return ResourceResult{}, err
+	}
+
+	if err := writeResourceEntries(projectDir, created, updated); err != nil {
+		return ResourceResult{}, err
+	}
+
+	result := ResourceResult{DB: engine}
+	for _, entry := range created {
+		result.Created = append(result.Created, entry.path)
+	}
+	for _, entry := range updated {
+		result.Updated = append(result.Updated, entry.path)
+	}
+	return result, nil
+}
+
+// readProjectModule returns the module path from the project's go.mod and
+// the SQL engine, detected from the GORM driver it requires unless db is
+// given. A project generated with --no-module has no go.mod; both come from
+// its lockfile instead.
+func readProjectModule(projectDir, db string) (string, string, error) {
+	modulePath, detected, source, err := detectProjectModule(projectDir)
+	if err != nil {
+		return "", "", err
+	}
+
+	engine := strings.ToLower(strings.TrimSpace(db))
+	switch {
+	case engine == "" && detected == "":
+		return "", "", fmt.Errorf(
+			"cannot detect the SQL engine from %s: resources need a project generated with --db mysql, postgres or sqlite",
+			source,
+		)
+	case engine == "":
+		engine = detected
+	case engine != "mysql" && engine != "postgres" && engine != "sqlite":
+		return "", "", fmt.Errorf("invalid db value %q: allowed values are mysql,postgres,sqlite", db)
+	case detected != "" && detected != engine:
+		return "", "", fmt.Errorf("project uses %s, not %s", detected, engine)
+	}
+
+	return modulePath, engine, nil
+}
+
+// detectProjectModule returns the module path and the SQL engine recorded
+// for the project, and the file they were read from.
+func detectProjectModule(projectDir string) (string, string, string, error) {
+	goModPath := filepath.Join(projectDir, "go.mod")
+	raw, err := os.ReadFile(goModPath)
+	if os.IsNotExist(err) {
+		lock, lockErr := ReadLockfile(projectDir)
+		if lockErr == nil && lock.Data.NoModule {
+			return lock.Data.ModuleName, lock.Data.SQLEngine(), filepath.Join(projectDir, LockfileName), nil
+		}
+	}
+	if err != nil {
+		return "", "", "", fmt.Errorf("read project go.mod: %w", err)
+	}
+	file, err := modfile.ParseLax(goModPath, raw, nil)
+	if err != nil {
+		return "", "", "", fmt.Errorf("parse project go.mod: %w", err)
+	}
+	if file.Module == nil {
+		return "", "", "", fmt.Errorf("project go.mod %s has no module directive", goModPath)
+	}
+
+	detected := ""
+	for _, req := range file.Require {
+		for _, driver := range sqlDriverModules {
+			if req.Mod.Path == driver.module {
+				detected = driver.engine
+			}
+		}
+	}
+	return file.Module.Mod.Path, detected, goModPath, nil
+}
+
+func newResourceData(modulePath, engine string, spec ResourceSpec) resourceData {
+	data := resourceData{
+		ModuleName: modulePath,
+		Engine:     engine,
+		Name:       spec.Name,
+		Type:       upperCamel(spec.Name),
+		Var:        lowerCamel(spec.Name),
+		VarPlural:  lowerCamel(pluralize(spec.Name)),
+		Table:      pluralize(spec.Name),
+		Route:      strings.ReplaceAll(pluralize(spec.Name), "_", "-"),
+	}
+	for _, field := range spec.Fields {
+		typ := resourceFieldTypes[field.Type]
+		updateType := "*" + typ.goType
+		if typ.nullable {
+			updateType = typ.goType
+		}
+		data.HasTime = data.HasTime || field.Type == "time"
+		data.Fields = append(data.Fields, resourceFieldData{
+			Column:     field.Name,
+			GoName:     upperCamel(field.Name),
+			GoType:     typ.goType,
+			UpdateType: updateType,
+			SQLType:    typ.sqlTypes[engine],
+			Nullable:   typ.nullable,
+		})
+	}
+	return data
+}
+
+func renderResource(projectDir string, data resourceData) ([]renderedEntry, error) {
+	var entries []renderedEntry
+	for _, file := range resourceFiles {
+		name := path.Join(resourceRoot, file.template)
+		raw, err := fs.ReadFile(resourceFS, name)
+		if err != nil {
+			return nil, fmt.Errorf("read template file %s: %w", name, err)
+		}
+		content, err := renderTemplate(name, raw, data)
+		if err != nil {
+			return nil, err
+		}
+
+		outRelPath := file.output(data)
+		if strings.HasSuffix(outRelPath, ".go") {
+			if content, err = format.Source(content); err != nil {
+				return nil, fmt.Errorf("format %s: %w", outRelPath, err)
+			}
+		}
+
+		outPath := filepath.Join(projectDir, filepath.FromSlash(outRelPath))
+		if _, err := os.Stat(outPath); err == nil {
+			return nil, fmt.Errorf("resource file already exists: %s", outPath)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("stat %s: %w", outPath, err)
+		}
+
+		entries = append(entries, renderedEntry{path: outRelPath, content: content})
+	}
+	return entries, nil
+}
+
+// registerResource returns the module files with the resource providers
+// added, plus the sqlite schema embed list when the engine needs it.
+func registerResource(projectDir string, data resourceData) ([]renderedEntry, error) {
+	var entries []renderedEntry
+	for _, provider := range resourceProviders {
+		filename := filepath.Join(projectDir, filepath.FromSlash(provider.file))
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", filename, err)
+		}
+		out, err := registerProvider(
+			filename,
+			src,
+			"Module",
+			data.ModuleName+"/"+provider.importPath,
+			provider.funcName(data),
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, renderedEntry{path: provider.file, content: out})
+	}
+
+	if data.Engine == "sqlite" {
+		filename := filepath.Join(projectDir, filepath.FromSlash(sqliteSchemaEmbedFile))
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", filename, err)
+		}
+		out, err := addSchemaEmbed(filename, src, data.Table+".sql")
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, renderedEntry{path: sqliteSchemaEmbedFile, content: out})
+	}
+	return entries, nil
+}
+
+// registerProvider appends pkg.funcName to the fx call assigned to varName,
+// where pkg is the name under which src imports importPath.
+func registerProvider(filename string, src []byte, varName, importPath, funcName string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filename, err)
+	}
+
+	pkgName := ""
+	for _, spec := range file.Imports {
+		if spec.Path.Value != strconv.Quote(importPath) {
+			continue
+		}
+		pkgName = path.Base(importPath)
+		if spec.Name != nil {
+			pkgName = spec.Name.Name
+		}
+	}
+	if pkgName == "" {
+		return nil, fmt.Errorf("%s does not import %s", filename, importPath)
+	}
+
+	call := findVarCall(file, varName)
+	if call == nil {
+		return nil, fmt.Errorf("%s has no `var %s = fx.Provide(...)` or fx.Invoke call", filename, varName)
+	}
+	for _, arg := range call.Args {
+		if sel, ok := arg.(*ast.SelectorExpr); ok && sel.Sel.Name == funcName {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == pkgName {
+				return nil, fmt.Errorf("%s already registers %s.%s", filename, pkgName, funcName)
+			}
+		}
+	}
+
+	rparen := fset.Position(call.Rparen).Offset
+	end := len(bytes.TrimRight(src[:rparen], " \t\r\n"))
+	var buf bytes.Buffer
+	buf.Write(src[:end])
+	if last := src[end-1]; last != '(' && last != ',' {
+		buf.WriteByte(',')
+	}
+	fmt.Fprintf(&buf, "\n\t%s.%s,\n", pkgName, funcName)
+	buf.Write(src[rparen:])
+
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format %s: %w", filename, err)
+	}
+	return out, nil
+}
+
+func findVarCall(file *ast.File, varName string) *ast.CallExpr {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value, ok := spec.(*ast.ValueSpec)
+			if !ok || len(value.Names) != 1 || len(value.Values) != 1 || value.Names[0].Name != varName {
+				continue
+			}
+			if call, ok := value.Values[0].(*ast.CallExpr); ok {
+				return call
+			}
+		}
+	}
+	return nil
+}
+
+// addSchemaEmbed appends name to the //go:embed directive of the sqlite
+// schema package.
+func addSchemaEmbed(filename string, src []byte, name string) ([]byte, error) {
+	lines := strings.Split(string(src), "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "//go:embed ") {
+			continue
+		}
+		for _, pattern := range strings.Fields(strings.TrimPrefix(line, "//go:embed ")) {
+			if pattern == name {
+				return nil, fmt.Errorf("%s already embeds %s", filename, name)
+			}
+		}
+		lines[i] = line + " " + name
+		return []byte(strings.Join(lines, "\n")), nil
+	}
+	return nil, fmt.Errorf("%s has no //go:embed directive", filename)
+}
+
+// writeResourceEntries writes the new files and the updated files of a
+// resource, undoing every write when one of them fails.
+func writeResourceEntries(projectDir string, created, updated []renderedEntry) (err error) {
+	var (
+		createdPaths []string
+		createdDirs  []string
+		restores     = make(map[string][]byte)
+	)
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, p := range createdPaths {
+			_ = os.Remove(p)
+		}
+		for _, dir := range createdDirs {
+			_ = os.RemoveAll(dir)
+		}
+		for p, content := range restores {
+			_ = os.WriteFile(p, content, 0o644)
+		}
+	}()
+
+	for _, entry := range created {
+		outPath := filepath.Join(projectDir, filepath.FromSlash(entry.path))
+		dir, err := mkdirAllTracked(filepath.Dir(outPath))
+		if err != nil {
+			return fmt.Errorf("create directory for %s: %w", outPath, err)
+		}
+		if dir != "" {
+			createdDirs = append(createdDirs, dir)
+		}
+
+		f, err := os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return fmt.Errorf("create %s: %w", outPath, err)
+		}
+		createdPaths = append(createdPaths, outPath)
+		_, err = f.Write(entry.content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("write %s: %w", outPath, err)
+		}
+	}
+
+	for _, entry := range updated {
+		outPath := filepath.Join(projectDir, filepath.FromSlash(entry.path))
+		original, err := os.ReadFile(outPath)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", outPath, err)
+		}
+		restores[outPath] = original
+		if err := os.WriteFile(outPath, entry.content, 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", outPath, err)
+		}
+	}
+
+	return nil
+}
+
+func upperCamel(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		if upper := strings.ToUpper(part); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func lowerCamel(name string) string {
+	first, rest, _ := strings.Cut(name, "_")
+	if rest == "" {
+		return first
+	}
+	return first + upperCamel(rest)
+}
+
+// commonInitialisms are upper-cased as a whole in Go names, as golint does.
+var commonInitialisms = map[string]bool{
+	"API": true, "HTTP": true, "ID": true, "IP": true, "JSON": true,
+	"SQL": true, "URL": true, "UUID": true,
+}
+
+// pluralize returns the English plural of a snake_case name by inflecting
+// its last word with the regular rules.
+func pluralize(name string) string {
+	switch {
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"),
+		strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsRune("aeiou", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	default:
+		return name + "s"
+	}
+}
@@ -0,0 +1,259 @@
+package scaf_fold
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseResourceFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []ResourceField
+		wantErr string
+	}{
+		{
+			name:  "trims spaces and lower-cases types",
+			input: " name:string , amount:INT64,paid_at:time ",
+			want: []ResourceField{
+				{Name: "name", Type: "string"},
+				{Name: "amount", Type: "int64"},
+				{Name: "paid_at", Type: "time"},
+			},
+		},
+		{name: "empty", input: " , ", wantErr: "at least one field is required"},
+		{name: "missing type", input: "name", wantErr: `invalid field "name": expected name:type`},
+		{name: "unknown type", input: "name:text", wantErr: `invalid type "text" for field "name"`},
+		{name: "camel case name", input: "unitPrice:float64", wantErr: `invalid field name "unitPrice"`},
+		{name: "reserved column", input: "created_at:time", wantErr: `field "created_at" is generated`},
+		{name: "duplicate", input: "name:string,name:int", wantErr: `duplicate field "name"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResourceFields(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseResourceFields(%q) error = %v, want %q", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseResourceFields(%q) error = %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseResourceFields(%q) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceNames(t *testing.T) {
+	for name, want := range map[string][3]string{
+		"order":      {"Order", "order", "orders"},
+		"order_item": {"OrderItem", "orderItem", "order_items"},
+		"category":   {"Category", "category", "categories"},
+		"key":        {"Key", "key", "keys"},
+		"box":        {"Box", "box", "boxes"},
+		"api_token":  {"APIToken", "apiToken", "api_tokens"},
+	} {
+		got := [3]string{upperCamel(name), lowerCamel(name), pluralize(name)}
+		if got != want {
+			t.Errorf("names of %q = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestAddResource(t *testing.T) {
+	outputDir := generateResourceProjectForTest(t, TemplateData{MySQL: true, MongoDB: true})
+
+	result, err := AddResource(outputDir, ResourceSpec{
+		Name:   "order",
+		Fields: []ResourceField{{Name: "name", Type: "string"}, {Name: "amount", Type: "int64"}},
+	})
+	if err != nil {
+		t.Fatalf("AddResource() error = %v", err)
+	}
+	if result.DB != "mysql" {
+		t.Fatalf("AddResource() detected db %q, want mysql", result.DB)
+	}
+	wantCreated := []string{
+		"internal/models/do/mysql/example_do/order.go",
+		"internal/models/vo/order.go",
+		"internal/repository/mysql/example_repo/order.go",
+		"internal/service/example_srv/order_service.go",
+		"internal/controller/example_controller/order_handler.go",
+		"docs/schema/orders.sql",
+	}
+	if !reflect.DeepEqual(result.Created, wantCreated) {
+		t.Fatalf("Created = %v, want %v", result.Created, wantCreated)
+	}
+	for _, relPath := range wantCreated {
+		assertFileExists(t, filepath.Join(outputDir, filepath.FromSlash(relPath)))
+	}
+
+	for relPath, want := range map[string]string{
+		"internal/repository/module.go": "mysql_example_repo.NewOrderRepository,\n)",
+		"internal/service/module.go":    "example_srv.NewOrderService,\n)",
+		"internal/controller/module.go": "example_controller.InitOrderController,\n)",
+	} {
+		content := readFileForAssertion(t, filepath.Join(outputDir, filepath.FromSlash(relPath)))
+		if !strings.Contains(content, want) {
+			t.Errorf("%s does not register %q:\n%s", relPath, want, content)
+		}
+	}
+
+	ddl := readFileForAssertion(t, filepath.Join(outputDir, "docs", "schema", "orders.sql"))
+	if !strings.Contains(ddl, "`amount` bigint NOT NULL DEFAULT 0,") {
+		t.Errorf("orders.sql missing amount column:\n%s", ddl)
+	}
+}
+
+func TestAddResourceRejectsExistingResource(t *testing.T) {
+	outputDir := generateResourceProjectForTest(t, TemplateData{Postgres: true})
+	fields := []ResourceField{{Name: "name", Type: "string"}}
+
+	if _, err := AddResource(outputDir, ResourceSpec{Name: "user", Fields: fields}); err == nil ||
+		!strings.Contains(err.Error(), "resource file already exists") {
+		t.Fatalf("AddResource(user) error = %v, want existing file error", err)
+	}
+	if _, err := AddResource(outputDir, ResourceSpec{Name: "order", Fields: fields}); err != nil {
+		t.Fatalf("AddResource(order) error = %v", err)
+	}
+
+	module := filepath.Join(outputDir, "internal", "service", "module.go")
+	before := readFileForAssertion(t, module)
+	if _, err := AddResource(outputDir, ResourceSpec{Name: "order", Fields: fields}); err == nil {
+		t.Fatal("second AddResource(order) should fail")
+	}
+	if after := readFileForAssertion(t, module); after != before {
+		t.Fatalf("failed AddResource changed %s:\n%s", module, after)
+	}
+}
+
+func TestAddResourceRejectsInvalidInput(t *testing.T) {
+	outputDir := generateResourceProjectForTest(t, TemplateData{MySQL: true})
+	fields := []ResourceField{{Name: "name", Type: "string"}}
+
+	for _, tt := range []struct {
+		spec    ResourceSpec
+		wantErr string
+	}{
+		{ResourceSpec{Name: "Order", Fields: fields}, `invalid resource name "Order"`},
+		{ResourceSpec{Name: "log", Fields: fields}, "clashes with an identifier"},
+		{ResourceSpec{Name: "order"}, "at least one field is required"},
+		{ResourceSpec{Name: "order", Fields: fields, DB: "postgres"}, "project uses mysql, not postgres"},
+	} {
+		if _, err := AddResource(outputDir, tt.spec); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("AddResource(%+v) error = %v, want %q", tt.spec, err, tt.wantErr)
+		}
+	}
+	assertFileNotExists(t, filepath.Join(outputDir, "internal", "models", "vo", "order.go"))
+
+	mongoDir := generateResourceProjectForTest(t, TemplateData{MongoDB: true})
+	if _, err := AddResource(mongoDir, ResourceSpec{Name: "order", Fields: fields}); err == nil ||
+		!strings.Contains(err.Error(), "cannot detect the SQL engine") {
+		t.Fatalf("AddResource() on mongodb project error = %v", err)
+	}
+}
+
+func TestAddResourceEmbedsSQLiteSchema(t *testing.T) {
+	outputDir := generateResourceProjectForTest(t, TemplateData{SQLite: true})
+
+	result, err := AddResource(outputDir, ResourceSpec{
+		Name:   "order_item",
+		Fields: []ResourceField{{Name: "paid_at", Type: "time"}},
+	})
+	if err != nil {
+		t.Fatalf("AddResource() error = %v", err)
+	}
+	if result.DB != "sqlite" {
+		t.Fatalf("AddResource() detected db %q, want sqlite", result.DB)
+	}
+
+	schema := readFileForAssertion(t, filepath.Join(outputDir, "docs", "schema", "schema.go"))
+	if !strings.Contains(schema, "//go:embed users.sql order_items.sql\n") {
+		t.Fatalf("schema.go does not embed order_items.sql:\n%s", schema)
+	}
+	ddl := readFileForAssertion(t, filepath.Join(outputDir, "docs", "schema", "order_items.sql"))
+	if !strings.Contains(ddl, "CREATE TABLE IF NOT EXISTS order_items (") {
+		t.Fatalf("order_items.sql is not idempotent:\n%s", ddl)
+	}
+}
+
+func TestRegisterProviderAppendsOnce(t *testing.T) {
+	src := []byte(`package service
+
+import (
+	"go.uber.org/fx"
+
+	srv "example.com/demo/internal/service/example_srv"
+)
+
+var Module = fx.Provide(srv.NewUserService)
+`)
+	out, err := registerProvider("module.go", src, "Module", "example.com/demo/internal/service/example_srv", "NewOrderService")
+	if err != nil {
+		t.Fatalf("registerProvider() error = %v", err)
+	}
+	want := "var Module = fx.Provide(srv.NewUserService,\n\tsrv.NewOrderService,\n)\n"
+	if !strings.HasSuffix(string(out), want) {
+		t.Fatalf("registerProvider() =\n%s\nwant suffix\n%s", out, want)
+	}
+
+	if _, err := registerProvider("module.go", out, "Module", "example.com/demo/internal/service/example_srv", "NewOrderService"); err == nil ||
+		!strings.Contains(err.Error(), "already registers srv.NewOrderService") {
+		t.Fatalf("second registerProvider() error = %v", err)
+	}
+}
+
+func TestAddResourceBuilds(t *testing.T) {
+	testAddResourceBuilds(t, false)
+}
+
+func testAddResourceBuilds(t *testing.T, runBuildChecks bool) {
+	fields := []ResourceField{
+		{Name: "name", Type: "string"},
+		{Name: "amount", Type: "int64"},
+		{Name: "qty", Type: "int32"},
+		{Name: "rank", Type: "int"},
+		{Name: "price", Type: "float64"},
+		{Name: "paid", Type: "bool"},
+		{Name: "paid_at", Type: "time"},
+	}
+
+	for _, data := range []TemplateData{{MySQL: true, MongoDB: true}, {Postgres: true}, {SQLite: true}} {
+		t.Run(data.SQLEngine(), func(t *testing.T) {
+			outputDir := generateResourceProjectForTest(t, data)
+			if _, err := AddResource(outputDir, ResourceSpec{Name: "order_item", Fields: fields}); err != nil {
+				t.Fatalf("AddResource() error = %v", err)
+			}
+
+			if runBuildChecks {
+				if stdout, stderr, err := runGoCommand(outputDir, "mod", "tidy"); err != nil {
+					t.Fatalf("go mod tidy failed: %v\nstdout:\n%s\nstderr:\n%s", err, stdout, stderr)
+				}
+				if stdout, stderr, err := runGoCommand(outputDir, "build", "./..."); err != nil {
+					t.Fatalf("go build failed: %v\nstdout:\n%s\nstderr:\n%s", err, stdout, stderr)
+				}
+				if stdout, stderr, err := runGoCommand(outputDir, "vet", "./..."); err != nil {
+					t.Fatalf("go vet failed: %v\nstdout:\n%s\nstderr:\n%s", err, stdout, stderr)
+				}
+			}
+		})
+	}
+}
+
+func generateResourceProjectForTest(t *testing.T, data TemplateData) string {
+	t.Helper()
+	outputDir := filepath.Join(t.TempDir(), "resource-web")
+	data.ModuleName = "github.com/test/resource-web"
+	data.BinaryName = "resource-web"
+	data.ProjectName = "resource-web"
+	if err := Generate(outputDir, data); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	return outputDir
+}
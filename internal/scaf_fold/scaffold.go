@@ -16,12 +16,27 @@ const templateRoot = "_template"
 const fallbackGoVersion = "1.26.0"
 
 type TemplateData struct {
-	ModuleName  string
-	BinaryName  string
-	ProjectName string
-	GoVersion   string
-	MySQL       bool
-	MongoDB     bool
+	ModuleName  string `json:"moduleName"`
+	BinaryName  string `json:"binaryName"`
+	ProjectName string `json:"projectName"`
+	GoVersion   string `json:"goVersion"`
+	MySQL       bool   `json:"mysql"`
+	Postgres    bool   `json:"postgres"`
+	SQLite      bool   `json:"sqlite"`
+	MongoDB     bool   `json:"mongodb"`
+	// Components lists the enabled optional components by name. A nil slice
+	// selects DefaultComponents.
+	Components []string `json:"components"`
+	// Extra holds custom template variables set with --set and --set-file,
+	// referenced in templates as {{ .Extra.key }}.
+	Extra map[string]string `json:"extra,omitempty"`
+	// NoModule renders the project as part of an enclosing module: no go.mod
+	// is generated and ModuleName is the import path of the output
+	// directory inside that module.
+	NoModule bool `json:"noModule,omitempty"`
+	// ModuleSubdir is the slash-separated path of the output directory
+	// relative to the root of the enclosing module when NoModule is set.
+	ModuleSubdir string `json:"moduleSubdir,omitempty"`
 }
 
 var (
@@ -29,23 +44,6 @@ var (
 	projectNamePattern   = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
 	goVersionPattern     = regexp.MustCompile(`^\d+\.\d+(?:\.\d+)?$`)
 	goVersionExtractExpr = regexp.MustCompile(`(\d+\.\d+(?:\.\d+)?)`)
-
-	mysqlOnlyTemplatePrefixes = []string{
-		templateRoot + "/internal/lib/gorm",
-		templateRoot + "/internal/lib/log/silent.go.tmpl",
-		templateRoot + "/internal/repository/mysql",
-		templateRoot + "/internal/models/do/mysql",
-		templateRoot + "/internal/controller/example_controller/user_handler.go.tmpl",
-		templateRoot + "/internal/service/example_srv/user_service.go.tmpl",
-		templateRoot + "/docs/schema",
-	}
-	mongoOnlyTemplatePrefixes = []string{
-		templateRoot + "/internal/lib/mongodb",
-		templateRoot + "/internal/repository/mongo",
-		templateRoot + "/internal/models/do/mongo",
-		templateRoot + "/internal/controller/example_controller/user_mongo_handler.go.tmpl",
-		templateRoot + "/internal/service/example_srv/user_mongo_service.go.tmpl",
-	}
 )
 
 func (d TemplateData) Validate() error {
@@ -66,18 +64,83 @@ func (d TemplateData) Validate() error {
 	if err := validateGoVersion(goVersion); err != nil {
 		return err
 	}
-	if !d.MySQL && !d.MongoDB {
+	if !d.SQL() && !d.MongoDB {
 		return fmt.Errorf("at least one database must be enabled")
 	}
+	if countTrue(d.MySQL, d.Postgres, d.SQLite) > 1 {
+		return fmt.Errorf("only one SQL database can be enabled: mysql, postgres or sqlite")
+	}
+	if err := validateComponents(d.enabledComponents()); err != nil {
+		return err
+	}
+	for key := range d.Extra {
+		if err := validateExtraKey(key); err != nil {
+			return err
+		}
+	}
+	if err := validateModuleSubdir(d.NoModule, d.ModuleSubdir); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func ParseDBFlag(val string) (mysql, mongodb bool, err error) {
+// SQL reports whether a GORM-backed SQL engine is enabled. Templates use it
+// for everything shared by the SQL engines and MySQL/Postgres for the
+// driver-specific parts.
+func (d TemplateData) SQL() bool {
+	return d.MySQL || d.Postgres || d.SQLite
+}
+
+// SQLEngine returns the GORM driver name of the enabled SQL engine, or ""
+// when none is enabled.
+func (d TemplateData) SQLEngine() string {
+	switch {
+	case d.MySQL:
+		return "mysql"
+	case d.Postgres:
+		return "postgres"
+	case d.SQLite:
+		return "sqlite"
+	default:
+		return ""
+	}
+}
+
+// databaseNames returns the --db names of the enabled databases.
+func (d TemplateData) databaseNames() []string {
+	var names []string
+	for _, db := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"mysql", d.MySQL},
+		{"postgres", d.Postgres},
+		{"sqlite", d.SQLite},
+		{"mongodb", d.MongoDB},
+	} {
+		if db.enabled {
+			names = append(names, db.name)
+		}
+	}
+	return names
+}
+
+// Databases is the engine selection parsed from --db. At most one SQL engine
+// can be selected, optionally together with MongoDB.
+type Databases struct {
+	MySQL    bool
+	Postgres bool
+	SQLite   bool
+	MongoDB  bool
+}
+
+func ParseDBFlag(val string) (Databases, error) {
 	return parseDBFlag(val)
 }
 
-func parseDBFlag(val string) (mysql, mongodb bool, err error) {
+func parseDBFlag(val string) (Databases, error) {
+	var dbs Databases
 	for _, rawToken := range strings.Split(val, ",") {
 		token := strings.ToLower(strings.TrimSpace(rawToken))
 		if token == "" {
@@ -86,89 +149,249 @@ func parseDBFlag(val string) (mysql, mongodb bool, err error) {
 
 		switch token {
 		case "mysql":
-			mysql = true
+			dbs.MySQL = true
+		case "postgres":
+			dbs.Postgres = true
+		case "sqlite":
+			dbs.SQLite = true
 		case "mongodb":
-			mongodb = true
+			dbs.MongoDB = true
 		default:
-			return false, false, fmt.Errorf(
-				"invalid db value %q: allowed values are mysql,mongodb",
+			return Databases{}, fmt.Errorf(
+				"invalid db value %q: allowed values are mysql,postgres,sqlite,mongodb",
 				rawToken,
 			)
 		}
 	}
 
-	if !mysql && !mongodb {
-		return false, false, fmt.Errorf("at least one database must be selected: mysql,mongodb")
+	sqlEngines := countTrue(dbs.MySQL, dbs.Postgres, dbs.SQLite)
+	if sqlEngines == 0 && !dbs.MongoDB {
+		return Databases{}, fmt.Errorf("at least one database must be selected: mysql,postgres,sqlite,mongodb")
+	}
+	if sqlEngines > 1 {
+		return Databases{}, fmt.Errorf("mysql, postgres and sqlite cannot be combined: select one SQL database")
+	}
+
+	return dbs, nil
+}
+
+func countTrue(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
 	}
+	return n
+}
+
+// Options controls where Generate and Plan load templates from and how
+// Generate treats files that already exist. The zero value renders the
+// embedded templates and fails on existing files with different content.
+type Options struct {
+	// TemplateDir replaces the embedded templates with the tree rooted at this
+	// directory. Files keep the same .tmpl suffix stripping, skip rules and
+	// rendering semantics.
+	TemplateDir string
+	// Overlays are template directories layered on top of the base templates
+	// in order. A file replaces or adds to the file at the same path below it,
+	// and a file named <path>.delete removes <path>, <path>.tmpl or the
+	// directory tree at <path> from the layers below.
+	Overlays []string
+	// OnConflict decides what Generate does with a file that already exists
+	// in the output directory with different content; empty means
+	// ConflictFail.
+	OnConflict ConflictStrategy
+	// Confirm is asked whether to overwrite relPath when OnConflict is
+	// ConflictPrompt.
+	Confirm func(relPath string) (bool, error)
+}
 
-	return mysql, mongodb, nil
+// PlannedEntry describes a directory or file that Generate would create,
+// relative to the output directory.
+type PlannedEntry struct {
+	Path  string
+	IsDir bool
+	Size  int
+}
+
+type renderedEntry struct {
+	path    string
+	isDir   bool
+	content []byte
+	// mode is the permission of a file entry; zero means 0644.
+	mode fs.FileMode
+	// origin is the template directory the file was rendered from, "" for
+	// the built-in templates.
+	origin string
+}
+
+func (e renderedEntry) perm() fs.FileMode {
+	if e.mode == 0 {
+		return 0o644
+	}
+	return e.mode
 }
 
 func Generate(outputDir string, data TemplateData) error {
+	_, err := GenerateWithOptions(outputDir, data, Options{})
+	return err
+}
+
+func GenerateWithOptions(outputDir string, data TemplateData, opts Options) (GenerateResult, error) {
 	data.applyDefaults()
 	if err := data.Validate(); err != nil {
-		return fmt.Errorf("invalid template data: %w", err)
+		return GenerateResult{}, fmt.Errorf("invalid template data: %w", err)
 	}
 
-	if err := prepareOutputDir(outputDir); err != nil {
-		return err
+	entries, err := renderProject(data, opts)
+	if err != nil {
+		return GenerateResult{}, err
+	}
+	if entries, err = withLockfile(outputDir, data, opts, entries); err != nil {
+		return GenerateResult{}, err
 	}
 
-	if err := fs.WalkDir(
-		templateFS,
-		templateRoot,
-		func(path string, d fs.DirEntry, walkErr error) error {
-			if walkErr != nil {
-				return fmt.Errorf("walk template path %s: %w", path, walkErr)
-			}
-			if path == templateRoot {
-				return nil
-			}
+	return writeProject(outputDir, entries, opts)
+}
 
-			if shouldSkipTemplate(path, data) {
-				if d.IsDir() {
-					return fs.SkipDir
-				}
-				return nil
-			}
+// Plan renders the templates for data in memory and reports what Generate
+// would write, without touching disk.
+func Plan(data TemplateData) ([]PlannedEntry, error) {
+	return PlanWithOptions(data, Options{})
+}
 
-			relPath := strings.TrimPrefix(path, templateRoot+"/")
-			outRelPath := strings.TrimSuffix(relPath, ".tmpl")
-			outPath := filepath.Join(outputDir, filepath.FromSlash(outRelPath))
+func PlanWithOptions(data TemplateData, opts Options) ([]PlannedEntry, error) {
+	data.applyDefaults()
+	if err := data.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid template data: %w", err)
+	}
 
-			if d.IsDir() {
-				if err := os.MkdirAll(outPath, 0o755); err != nil {
-					return fmt.Errorf("create directory %s: %w", outPath, err)
-				}
-				return nil
+	entries, err := renderProject(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	if entries, err = withLockfile("", data, opts, entries); err != nil {
+		return nil, err
+	}
+
+	planned := make([]PlannedEntry, 0, len(entries))
+	for _, entry := range entries {
+		planned = append(planned, PlannedEntry{
+			Path:  entry.path,
+			IsDir: entry.isDir,
+			Size:  len(entry.content),
+		})
+	}
+
+	return planned, nil
+}
+
+func renderProject(data TemplateData, opts Options) ([]renderedEntry, error) {
+	set, err := loadTemplateSet(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []renderedEntry
+	createdDirs := make(map[string]bool)
+	// renderedFrom maps each output path to the template it came from.
+	renderedFrom := make(map[string]string)
+	checkedGo := make(map[string]string)
+	for _, file := range set.files {
+		include, err := set.manifest.includes(file.path, data)
+		if err != nil {
+			return nil, err
+		}
+		if !include {
+			continue
+		}
+
+		outRelPath, err := renderPath(file.displayPath(), strings.TrimSuffix(file.path, ".tmpl"), data)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := renderedFrom[outRelPath]; ok {
+			return nil, fmt.Errorf("templates %s and %s both render to %s", other, file.displayPath(), outRelPath)
+		}
+		renderedFrom[outRelPath] = file.displayPath()
+		for _, dir := range parentDirs(outRelPath) {
+			if createdDirs[dir] {
+				continue
 			}
+			createdDirs[dir] = true
+			entries = append(entries, renderedEntry{path: dir, isDir: true})
+		}
 
-			raw, err := templateFS.ReadFile(path)
-			if err != nil {
-				return fmt.Errorf("read template file %s: %w", path, err)
+		raw, err := fs.ReadFile(file.src.fsys, file.path)
+		if err != nil {
+			return nil, fmt.Errorf("read template file %s: %w", file.displayPath(), err)
+		}
+		rule, _ := set.manifest.match(file.path)
+		content := raw
+		if strings.HasSuffix(file.path, ".tmpl") && !rule.Raw {
+			if content, err = renderTemplate(file.displayPath(), raw, data); err != nil {
+				return nil, err
 			}
-			rendered, err := renderTemplate(path, raw, data)
-			if err != nil {
-				return err
+			if strings.HasSuffix(outRelPath, ".go") {
+				if content, err = formatGoSource(file.displayPath(), outRelPath, content); err != nil {
+					return nil, err
+				}
+				checkedGo[outRelPath] = file.displayPath()
 			}
+		}
+		mode, err := file.mode(rule)
+		if err != nil {
+			return nil, err
+		}
 
-			outParentDir := filepath.Dir(outPath)
-			if err := os.MkdirAll(outParentDir, 0o755); err != nil {
-				return fmt.Errorf(
-					"create output parent directory %s for file %s: %w",
-					outParentDir,
-					outPath,
-					err,
-				)
-			}
-			if err := os.WriteFile(outPath, rendered, 0o644); err != nil {
-				return fmt.Errorf("write output file %s: %w", outPath, err)
+		entries = append(entries, renderedEntry{
+			path:    outRelPath,
+			content: content,
+			mode:    mode,
+			origin:  file.src.dir,
+		})
+	}
+
+	if err := checkGoPackages(data.ModuleName, entries, checkedGo); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parentDirs returns the ancestors of a slash-separated path, outermost first.
+func parentDirs(relPath string) []string {
+	var dirs []string
+	for i, r := range relPath {
+		if r == '/' {
+			dirs = append(dirs, relPath[:i])
+		}
+	}
+	return dirs
+}
+
+func writeEntries(outputDir string, entries []renderedEntry) error {
+	for _, entry := range entries {
+		outPath := filepath.Join(outputDir, filepath.FromSlash(entry.path))
+		if entry.isDir {
+			if err := os.MkdirAll(outPath, 0o755); err != nil {
+				return fmt.Errorf("create directory %s: %w", outPath, err)
 			}
+			continue
+		}
 
-			return nil
-		},
-	); err != nil {
-		return fmt.Errorf("walk templates: %w", err)
+		outParentDir := filepath.Dir(outPath)
+		if err := os.MkdirAll(outParentDir, 0o755); err != nil {
+			return fmt.Errorf(
+				"create output parent directory %s for file %s: %w",
+				outParentDir,
+				outPath,
+				err,
+			)
+		}
+		if err := os.WriteFile(outPath, entry.content, entry.perm()); err != nil {
+			return fmt.Errorf("write output file %s: %w", outPath, err)
+		}
 	}
 
 	return nil
@@ -189,69 +412,33 @@ func defaultGoVersion() string {
 	return matches[1]
 }
 
-func shouldSkipTemplate(path string, data TemplateData) bool {
-	path = filepath.ToSlash(path)
-	if !data.MySQL && anyTemplatePrefixMatches(path, mysqlOnlyTemplatePrefixes) {
-		return true
-	}
-	if !data.MongoDB && anyTemplatePrefixMatches(path, mongoOnlyTemplatePrefixes) {
-		return true
-	}
-	return false
-}
-
-func anyTemplatePrefixMatches(path string, prefixes []string) bool {
-	for _, prefix := range prefixes {
-		if path == prefix {
-			return true
-		}
-		if strings.HasSuffix(prefix, ".tmpl") {
-			continue
-		}
-		if strings.HasPrefix(path, prefix+"/") {
-			return true
-		}
-	}
-	return false
-}
-
-func prepareOutputDir(outputDir string) error {
+// prepareOutputDir checks that outputDir is a directory if it exists, and
+// reports whether it does.
+func prepareOutputDir(outputDir string) (bool, error) {
 	info, err := os.Stat(outputDir)
 	if err == nil {
 		if !info.IsDir() {
-			return fmt.Errorf("output path is not a directory: %s", outputDir)
-		}
-
-		entries, err := os.ReadDir(outputDir)
-		if err != nil {
-			return fmt.Errorf("read output directory %s: %w", outputDir, err)
+			return false, fmt.Errorf("output path is not a directory: %s", outputDir)
 		}
-		if hasVisibleEntries(entries) {
-			return fmt.Errorf("output directory is not empty: %s", outputDir)
-		}
-
-		return nil
+		return true, nil
 	}
 	if !os.IsNotExist(err) {
-		return fmt.Errorf("stat output directory %s: %w", outputDir, err)
-	}
-
-	if err := os.MkdirAll(outputDir, 0o755); err != nil {
-		return fmt.Errorf("create output directory %s: %w", outputDir, err)
+		return false, fmt.Errorf("stat output directory %s: %w", outputDir, err)
 	}
 
-	return nil
+	return false, nil
 }
 
-func hasVisibleEntries(entries []os.DirEntry) bool {
-	for _, entry := range entries {
-		if entry.Name() == ".git" {
-			continue
-		}
-		return true
-	}
+// ValidateModulePath reports why moduleName cannot be the module path of a
+// generated project, or nil when it can.
+func ValidateModulePath(moduleName string) error {
+	return validateModulePath(moduleName)
+}
 
-	return false
+// ValidateBinaryName reports why binaryName cannot be the binary name of a
+// generated project, or nil when it can.
+func ValidateBinaryName(binaryName string) error {
+	return validateBinaryName(binaryName)
 }
 
 func validateModulePath(moduleName string) error {
@@ -7,3 +7,7 @@ import "testing"
 func TestGenerateE2EDBCombosIntegration(t *testing.T) {
 	testGenerateE2EDBCombos(t, true)
 }
+
+func TestAddResourceBuildsIntegration(t *testing.T) {
+	testAddResourceBuilds(t, true)
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -55,7 +56,9 @@ func TestGenerate(t *testing.T) {
 	}
 }
 
-func TestGenerateRejectsNonEmptyOutputDir(t *testing.T) {
+// The default ConflictFail strategy only rejects generated paths that
+// already exist with other content; unrelated files do not block generation.
+func TestGenerateIntoNonEmptyOutputDir(t *testing.T) {
 	baseDir := t.TempDir()
 	outputDir := filepath.Join(baseDir, "existing")
 
@@ -73,12 +76,13 @@ func TestGenerateRejectsNonEmptyOutputDir(t *testing.T) {
 		MySQL:       true,
 		MongoDB:     true,
 	})
-	if err == nil {
-		t.Fatal("Generate() expected error, got nil")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
 	}
-	if !strings.Contains(err.Error(), "not empty") {
-		t.Fatalf("Generate() unexpected error: %v", err)
+	if got := readFileForAssertion(t, filepath.Join(outputDir, "placeholder.txt")); got != "x" {
+		t.Fatalf("placeholder.txt changed to %q", got)
 	}
+	assertFileExists(t, filepath.Join(outputDir, "go.mod"))
 }
 
 func TestGenerateAllowsHiddenEntriesOnly(t *testing.T) {
@@ -101,7 +105,7 @@ func TestGenerateAllowsHiddenEntriesOnly(t *testing.T) {
 	}
 }
 
-func TestGenerateRejectsHiddenEntriesExceptGit(t *testing.T) {
+func TestGenerateFailsOnConflictingFiles(t *testing.T) {
 	baseDir := t.TempDir()
 	outputDir := filepath.Join(baseDir, "existing")
 	if err := os.MkdirAll(outputDir, 0o755); err != nil {
@@ -119,11 +123,12 @@ func TestGenerateRejectsHiddenEntriesExceptGit(t *testing.T) {
 		MongoDB:     true,
 	})
 	if err == nil {
-		t.Fatal("Generate() expected error for hidden non-.git entry, got nil")
+		t.Fatal("Generate() expected error for conflicting .gitignore, got nil")
 	}
-	if !strings.Contains(err.Error(), "not empty") {
+	if !strings.Contains(err.Error(), "1 file(s) already exist") || !strings.Contains(err.Error(), ".gitignore") {
 		t.Fatalf("Generate() unexpected error: %v", err)
 	}
+	assertDirEntries(t, outputDir, ".gitignore")
 }
 
 func TestGenerateRejectsInvalidTemplateData(t *testing.T) {
@@ -143,19 +148,444 @@ func TestGenerateRejectsInvalidTemplateData(t *testing.T) {
 	}
 }
 
+func TestGenerateRejectsTwoSQLDatabases(t *testing.T) {
+	outputDir := filepath.Join(t.TempDir(), "out")
+	err := Generate(outputDir, TemplateData{
+		ModuleName:  "github.com/test/sample",
+		BinaryName:  "sample",
+		ProjectName: "sample",
+		MySQL:       true,
+		SQLite:      true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "only one SQL database") {
+		t.Fatalf("Generate() error = %v, want single SQL database error", err)
+	}
+	assertFileNotExists(t, outputDir)
+}
+
+func TestGenerateLeavesNoStagingDirectory(t *testing.T) {
+	baseDir := t.TempDir()
+	outputDir := filepath.Join(baseDir, "staged-web")
+
+	if err := Generate(outputDir, TemplateData{
+		ModuleName:  "github.com/test/staged-web",
+		BinaryName:  "staged-web",
+		ProjectName: "staged-web",
+		MySQL:       true,
+	}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	info, err := os.Stat(outputDir)
+	if err != nil {
+		t.Fatalf("stat output dir: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o755 {
+		t.Fatalf("output dir permissions = %o, want 755", perm)
+	}
+	assertDirEntries(t, baseDir, "staged-web")
+}
+
+func TestWriteProjectFailureLeavesNoOutput(t *testing.T) {
+	baseDir := t.TempDir()
+	outputDir := filepath.Join(baseDir, "nested", "broken-web")
+
+	_, err := writeProject(outputDir, brokenEntriesForTest(), Options{})
+	if err == nil {
+		t.Fatal("writeProject() expected error, got nil")
+	}
+
+	assertFileNotExists(t, filepath.Join(baseDir, "nested"))
+	assertDirEntries(t, baseDir)
+}
+
+func TestWriteProjectFailureKeepsExistingEntries(t *testing.T) {
+	baseDir := t.TempDir()
+	outputDir := filepath.Join(baseDir, "existing")
+	if err := os.MkdirAll(filepath.Join(outputDir, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir .git dir: %v", err)
+	}
+	headPath := filepath.Join(outputDir, ".git", "HEAD")
+	if err := os.WriteFile(headPath, []byte("ref: refs/heads/main\n"), 0o644); err != nil {
+		t.Fatalf("write .git/HEAD: %v", err)
+	}
+
+	if _, err := writeProject(outputDir, brokenEntriesForTest(), Options{}); err == nil {
+		t.Fatal("writeProject() expected error, got nil")
+	}
+
+	assertDirEntries(t, baseDir, "existing")
+	assertDirEntries(t, outputDir, ".git")
+	if got := readFileForAssertion(t, headPath); got != "ref: refs/heads/main\n" {
+		t.Fatalf(".git/HEAD changed to %q", got)
+	}
+
+	if err := Generate(outputDir, TemplateData{
+		ModuleName:  "github.com/test/existing",
+		BinaryName:  "existing",
+		ProjectName: "existing",
+		MongoDB:     true,
+	}); err != nil {
+		t.Fatalf("Generate() retry after failure error = %v", err)
+	}
+	assertFileExists(t, filepath.Join(outputDir, "go.mod"))
+}
+
+// brokenEntriesForTest returns entries whose second file cannot be written
+// because its parent path is already a regular file.
+func brokenEntriesForTest() []renderedEntry {
+	return []renderedEntry{
+		{path: "app", isDir: true},
+		{path: "app/main.go", content: []byte("package main\n")},
+		{path: "app/main.go/nested.go", content: []byte("package main\n")},
+	}
+}
+
+func TestGenerateFromTemplateDir(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"go.mod.tmpl":                        "module {{ .ModuleName }}\n",
+		"README.md":                          "# {{ .ProjectName }}\n",
+		"internal/lib/gorm/gorm.go.tmpl":     "package gormv2\n",
+		"internal/lib/mongodb/mongo.go.tmpl": "package mongodb\n",
+		".git/HEAD":                          "ref: refs/heads/main\n",
+		"manifest.yaml": "rules:\n" +
+			"  - path: internal/lib/gorm\n    when: .MySQL\n" +
+			"  - path: internal/lib/mongodb\n    when: .MongoDB\n",
+	})
+
+	outputDir := filepath.Join(t.TempDir(), "custom-web")
+	_, err := GenerateWithOptions(outputDir, TemplateData{
+		ModuleName:  "github.com/test/custom-web",
+		BinaryName:  "custom-web",
+		ProjectName: "custom-web",
+		MongoDB:     true,
+	}, Options{TemplateDir: templateDir})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions() error = %v", err)
+	}
+
+	if got := readFileForAssertion(t, filepath.Join(outputDir, "go.mod")); got != "module github.com/test/custom-web\n" {
+		t.Fatalf("go.mod = %q", got)
+	}
+	if got := readFileForAssertion(t, filepath.Join(outputDir, "README.md")); got != "# {{ .ProjectName }}\n" {
+		t.Fatalf("README.md without .tmpl suffix should be copied verbatim, got %q", got)
+	}
+	assertFileExists(t, filepath.Join(outputDir, "internal", "lib", "mongodb", "mongo.go"))
+	assertFileNotExists(t, filepath.Join(outputDir, "internal", "lib", "gorm"))
+	assertFileNotExists(t, filepath.Join(outputDir, ".git"))
+	assertFileNotExists(t, filepath.Join(outputDir, "manifest.yaml"))
+	assertFileNotExists(t, filepath.Join(outputDir, "app"))
+}
+
+func TestGenerateCopiesRawFilesAndKeepsModes(t *testing.T) {
+	favicon := "\x00\x00\x01\x00{{ not a template }}\xff"
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"go.mod.tmpl":                "module {{ .ModuleName }}\n",
+		"static/favicon.ico":         favicon,
+		"scripts/dev.sh":             "#!/bin/sh\ngo run ./app/main.go http\n",
+		"scripts/release.sh.tmpl":    "#!/bin/sh\nmake build BINARY={{ .BinaryName }}\n",
+		"docs/snippets/main.go.tmpl": "// {{ .ModuleName }} is rendered by the project's own tooling\n",
+		"manifest.yaml": "rules:\n" +
+			"  - path: scripts/release.sh.tmpl\n    mode: \"0755\"\n" +
+			"  - path: docs/snippets\n    raw: true\n",
+	})
+	if err := os.Chmod(filepath.Join(templateDir, "scripts", "dev.sh"), 0o755); err != nil {
+		t.Fatalf("chmod dev.sh: %v", err)
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "raw-web")
+	_, err := GenerateWithOptions(outputDir, TemplateData{
+		ModuleName:  "github.com/test/raw-web",
+		BinaryName:  "raw-web",
+		ProjectName: "raw-web",
+		MongoDB:     true,
+	}, Options{TemplateDir: templateDir})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions() error = %v", err)
+	}
+
+	if got := readFileForAssertion(t, filepath.Join(outputDir, "static", "favicon.ico")); got != favicon {
+		t.Fatalf("favicon.ico = %q, want byte-for-byte copy", got)
+	}
+	if got := readFileForAssertion(t, filepath.Join(outputDir, "docs", "snippets", "main.go")); got !=
+		"// {{ .ModuleName }} is rendered by the project's own tooling\n" {
+		t.Fatalf("raw manifest file was rendered: %q", got)
+	}
+	if got := readFileForAssertion(t, filepath.Join(outputDir, "scripts", "release.sh")); got !=
+		"#!/bin/sh\nmake build BINARY=raw-web\n" {
+		t.Fatalf("release.sh = %q", got)
+	}
+
+	for name, want := range map[string]os.FileMode{
+		"scripts/dev.sh":     0o755,
+		"scripts/release.sh": 0o755,
+		"static/favicon.ico": 0o644,
+		"go.mod":             0o644,
+	} {
+		info, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("stat %s: %v", name, err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s mode = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestGenerateFromTemplateDirReportsTemplatePath(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{
+		"go.mod.tmpl": "module {{ .Missing }}\n",
+	})
+
+	_, err := PlanWithOptions(TemplateData{
+		ModuleName:  "github.com/test/custom-web",
+		BinaryName:  "custom-web",
+		ProjectName: "custom-web",
+		MySQL:       true,
+	}, Options{TemplateDir: templateDir})
+	if err == nil {
+		t.Fatal("PlanWithOptions() expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), filepath.Join(templateDir, "go.mod.tmpl")) {
+		t.Fatalf("PlanWithOptions() error should name the template file: %v", err)
+	}
+}
+
+func TestGenerateRejectsMissingTemplateDir(t *testing.T) {
+	missingDir := filepath.Join(t.TempDir(), "missing")
+	outputDir := filepath.Join(t.TempDir(), "out")
+	_, err := GenerateWithOptions(outputDir, TemplateData{
+		ModuleName:  "github.com/test/out",
+		BinaryName:  "out",
+		ProjectName: "out",
+		MySQL:       true,
+	}, Options{TemplateDir: missingDir})
+	if err == nil {
+		t.Fatal("GenerateWithOptions() expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "open template directory") {
+		t.Fatalf("GenerateWithOptions() unexpected error: %v", err)
+	}
+	assertFileNotExists(t, outputDir)
+}
+
+func TestGenerateWithOverlays(t *testing.T) {
+	overlayDir := t.TempDir()
+	writeTemplateTreeForTest(t, overlayDir, map[string]string{
+		"internal/http/middleware.go.tmpl": "package http // overlay for {{ .ModuleName }}\n\nfunc InitMiddleware(any) {}\n",
+		"internal/lib/log/lark_stub.go.tmpl": "package log\n\ntype LarkZapLogger struct{}\n\n" +
+			"func NewLarkZapLogger(...any) *LarkZapLogger { return nil }\n",
+		"internal/lib/kafka/kafka.go.tmpl":            "package kafka\n",
+		"internal/lib/gorm/extra.go.tmpl":             "package gormv2\n",
+		"internal/lib/log/lark_logger.go.tmpl.delete": "",
+		"internal/cron.delete":                        "",
+	})
+
+	outputDir := filepath.Join(t.TempDir(), "overlay-web")
+	_, err := GenerateWithOptions(outputDir, TemplateData{
+		ModuleName:  "github.com/test/overlay-web",
+		BinaryName:  "overlay-web",
+		ProjectName: "overlay-web",
+		MongoDB:     true,
+	}, Options{Overlays: []string{overlayDir}})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions() error = %v", err)
+	}
+
+	middleware := readFileForAssertion(t, filepath.Join(outputDir, "internal", "http", "middleware.go"))
+	if middleware != "package http // overlay for github.com/test/overlay-web\n\nfunc InitMiddleware(any) {}\n" {
+		t.Fatalf("middleware.go was not replaced by overlay: %q", middleware)
+	}
+	assertFileExists(t, filepath.Join(outputDir, "internal", "lib", "kafka", "kafka.go"))
+	assertFileExists(t, filepath.Join(outputDir, "internal", "http", "server.go"))
+	assertFileNotExists(t, filepath.Join(outputDir, "internal", "lib", "log", "lark_logger.go"))
+	assertFileNotExists(t, filepath.Join(outputDir, "internal", "lib", "log", "lark_logger.go.tmpl.delete"))
+	assertFileNotExists(t, filepath.Join(outputDir, "internal", "cron"))
+	assertFileNotExists(t, filepath.Join(outputDir, "internal", "lib", "gorm"))
+}
+
+func TestOverlayDeleteMarkerKeepsFilesOfItsOwnLayer(t *testing.T) {
+	overlayDir := t.TempDir()
+	writeTemplateTreeForTest(t, overlayDir, map[string]string{
+		"internal/cron.delete":       "",
+		"internal/cron/jobs.go.tmpl": "package cron\n",
+		"README.md.tmpl.delete":      "",
+		"README.md.tmpl":             "# {{ .ProjectName }}\n",
+	})
+
+	set, err := loadTemplateSet(Options{Overlays: []string{overlayDir}})
+	if err != nil {
+		t.Fatalf("loadTemplateSet() error = %v", err)
+	}
+	var cronFiles []string
+	var readme templateFile
+	for _, file := range set.files {
+		if strings.HasPrefix(file.path, "internal/cron/") {
+			cronFiles = append(cronFiles, file.path)
+		}
+		if file.path == "README.md.tmpl" {
+			readme = file
+		}
+	}
+	if !reflect.DeepEqual(cronFiles, []string{"internal/cron/jobs.go.tmpl"}) {
+		t.Fatalf("internal/cron files = %q, want only the overlay's jobs.go.tmpl", cronFiles)
+	}
+	if readme.path == "" || readme.displayPath() != filepath.Join(overlayDir, "README.md.tmpl") {
+		t.Fatalf("README.md.tmpl = %#v, want the overlay's file", readme)
+	}
+}
+
+func TestGenerateWithTemplatedPaths(t *testing.T) {
+	overlayDir := t.TempDir()
+	writeTemplateTreeForTest(t, overlayDir, map[string]string{
+		"cmd/{{ .BinaryName }}/main.go.tmpl":                        "package main\n",
+		"docs/{{ .ProjectName | snake }}_{{ .Extra.team }}.md.tmpl": "# {{ .ProjectName }}\n",
+	})
+
+	outputDir := filepath.Join(t.TempDir(), "path-web")
+	_, err := GenerateWithOptions(outputDir, TemplateData{
+		ModuleName:  "github.com/test/path-web",
+		BinaryName:  "path-api",
+		ProjectName: "path-web",
+		MongoDB:     true,
+		Extra:       map[string]string{"team": "core"},
+	}, Options{Overlays: []string{overlayDir}})
+	if err != nil {
+		t.Fatalf("GenerateWithOptions() error = %v", err)
+	}
+
+	assertFileExists(t, filepath.Join(outputDir, "cmd", "path-api", "main.go"))
+	if got := readFileForAssertion(t, filepath.Join(outputDir, "docs", "path_web_core.md")); got != "# path-web\n" {
+		t.Fatalf("docs/path_web_core.md = %q", got)
+	}
+	lockfile := readFileForAssertion(t, filepath.Join(outputDir, LockfileName))
+	if !strings.Contains(lockfile, `"cmd/path-api/main.go"`) {
+		t.Fatalf("lockfile does not record the rendered path:\n%s", lockfile)
+	}
+}
+
+func TestPlanRejectsUnsafeTemplatedPaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		dir   string
+		want  string
+	}{
+		{name: "empty", files: map[string]string{"{{ .Extra.dir }}/a.go": ""}, dir: "", want: `renders to unsafe name ""`},
+		{name: "parent", files: map[string]string{"{{ .Extra.dir }}/a.go": ""}, dir: "..", want: `renders to unsafe name ".."`},
+		{name: "separator", files: map[string]string{"{{ .Extra.dir }}.go": ""}, dir: "a/b", want: `renders to unsafe name "a/b.go"`},
+		{
+			name:  "duplicate",
+			files: map[string]string{"{{ .Extra.dir }}.txt": "", "same.txt.tmpl": ""},
+			dir:   "same",
+			want:  "both render to same.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overlayDir := t.TempDir()
+			writeTemplateTreeForTest(t, overlayDir, tt.files)
+
+			_, err := PlanWithOptions(TemplateData{
+				ModuleName:  "github.com/test/unsafe-web",
+				BinaryName:  "unsafe-web",
+				ProjectName: "unsafe-web",
+				MongoDB:     true,
+				Extra:       map[string]string{"dir": tt.dir},
+			}, Options{Overlays: []string{overlayDir}})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("PlanWithOptions() error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanMatchesGenerate(t *testing.T) {
+	data := TemplateData{
+		ModuleName:  "github.com/test/plan-web",
+		BinaryName:  "plan-web",
+		ProjectName: "plan-web",
+		MySQL:       true,
+		MongoDB:     false,
+	}
+
+	entries, err := Plan(data)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+
+	outputDir := filepath.Join(t.TempDir(), "plan-web")
+	if err := Generate(outputDir, data); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	planned := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		planned[entry.Path] = true
+		outPath := filepath.Join(outputDir, filepath.FromSlash(entry.Path))
+		info, err := os.Stat(outPath)
+		if err != nil {
+			t.Fatalf("planned entry %s was not generated: %v", entry.Path, err)
+		}
+		if info.IsDir() != entry.IsDir {
+			t.Fatalf("planned entry %s IsDir = %v, generated IsDir = %v", entry.Path, entry.IsDir, info.IsDir())
+		}
+		if !entry.IsDir && int64(entry.Size) != info.Size() {
+			t.Fatalf("planned entry %s Size = %d, generated size = %d", entry.Path, entry.Size, info.Size())
+		}
+	}
+
+	if err := filepath.WalkDir(outputDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || path == outputDir {
+			return err
+		}
+		rel, err := filepath.Rel(outputDir, path)
+		if err != nil {
+			return err
+		}
+		if !planned[filepath.ToSlash(rel)] {
+			t.Errorf("generated entry %s missing from plan", rel)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("walk generated output: %v", err)
+	}
+}
+
+func TestPlanRejectsInvalidTemplateData(t *testing.T) {
+	_, err := Plan(TemplateData{
+		ModuleName:  "github.com/test/plan-web",
+		BinaryName:  "plan-web",
+		ProjectName: "plan-web",
+	})
+	if err == nil {
+		t.Fatal("Plan() expected error without databases, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid template data") {
+		t.Fatalf("Plan() unexpected error: %v", err)
+	}
+}
+
 func TestGenerateE2EDBCombos(t *testing.T) {
 	testGenerateE2EDBCombos(t, false)
 }
 
 func testGenerateE2EDBCombos(t *testing.T, runBuildChecks bool) {
 	tests := []struct {
-		name      string
-		mysql     bool
-		mongodb   bool
-		module    string
-		binary    string
-		project   string
-		leakCheck func(*testing.T, string)
+		name       string
+		mysql      bool
+		postgres   bool
+		sqlite     bool
+		mongodb    bool
+		components []string
+		module     string
+		binary     string
+		project    string
+		leakCheck  func(*testing.T, string)
 	}{
 		{
 			name:    "mysql-only",
@@ -196,6 +626,84 @@ func testGenerateE2EDBCombos(t *testing.T, runBuildChecks bool) {
 				}
 			},
 		},
+		{
+			name:     "postgres-only",
+			postgres: true,
+			module:   "github.com/test/postgres-only-web",
+			binary:   "postgres-only-web",
+			project:  "postgres-only-web",
+			leakCheck: func(t *testing.T, outputDir string) {
+				t.Helper()
+				goMod := readFileForAssertion(t, filepath.Join(outputDir, "go.mod"))
+				if !strings.Contains(goMod, "gorm.io/driver/postgres") {
+					t.Fatalf("postgres-only go.mod should contain postgres gorm driver dependency")
+				}
+				if strings.Contains(goMod, "gorm.io/driver/mysql") {
+					t.Fatalf("postgres-only go.mod should not contain mysql gorm driver dependency")
+				}
+				if strings.Contains(goMod, "github.com/go-sql-driver/mysql") {
+					t.Fatalf("postgres-only go.mod should not contain mysql driver dependency")
+				}
+				schema := readFileForAssertion(t, filepath.Join(outputDir, "docs", "schema", "users.sql"))
+				if strings.Contains(schema, "`") || strings.Contains(schema, "AUTO_INCREMENT") {
+					t.Fatalf("postgres schema should not contain MySQL syntax:\n%s", schema)
+				}
+				configYAML := readFileForAssertion(t, filepath.Join(outputDir, "config", "config.yml"))
+				for _, key := range []string{`driver: "postgres"`, "sslMode:", "searchPath:"} {
+					if !strings.Contains(configYAML, key) {
+						t.Fatalf("postgres config.yml should contain %q:\n%s", key, configYAML)
+					}
+				}
+			},
+		},
+		{
+			name:       "sqlite-without-redis",
+			sqlite:     true,
+			components: []string{"cron", "jwt"},
+			module:     "github.com/test/sqlite-web",
+			binary:     "sqlite-web",
+			project:    "sqlite-web",
+			leakCheck: func(t *testing.T, outputDir string) {
+				t.Helper()
+				goMod := readFileForAssertion(t, filepath.Join(outputDir, "go.mod"))
+				if !strings.Contains(goMod, "github.com/glebarez/sqlite") {
+					t.Fatalf("sqlite go.mod should contain pure-Go sqlite driver dependency")
+				}
+				for _, module := range []string{"gorm.io/driver/mysql", "gorm.io/driver/postgres", "github.com/mattn/go-sqlite3"} {
+					if strings.Contains(goMod, module) {
+						t.Fatalf("sqlite go.mod should not contain %s", module)
+					}
+				}
+				assertFileExists(t, filepath.Join(outputDir, "docs", "schema", "schema.go"))
+				assertFileExists(t, filepath.Join(outputDir, "internal", "lib", "gorm", "schema.go"))
+				schema := readFileForAssertion(t, filepath.Join(outputDir, "docs", "schema", "users.sql"))
+				if !strings.Contains(schema, "CREATE TABLE IF NOT EXISTS users") {
+					t.Fatalf("sqlite schema should be safe to run at every startup:\n%s", schema)
+				}
+				configYAML := readFileForAssertion(t, filepath.Join(outputDir, "config", "config.yml"))
+				if !strings.Contains(configYAML, `path: "data/sqlite-web.db"`) {
+					t.Fatalf("sqlite config.yml should contain the database file path:\n%s", configYAML)
+				}
+			},
+		},
+		{
+			name:     "postgres-and-mongodb",
+			postgres: true,
+			mongodb:  true,
+			module:   "github.com/test/postgres-mongo-web",
+			binary:   "postgres-mongo-web",
+			project:  "postgres-mongo-web",
+			leakCheck: func(t *testing.T, outputDir string) {
+				t.Helper()
+				goMod := readFileForAssertion(t, filepath.Join(outputDir, "go.mod"))
+				if !strings.Contains(goMod, "gorm.io/driver/postgres") {
+					t.Fatalf("postgres go.mod should contain postgres gorm driver dependency")
+				}
+				if !strings.Contains(goMod, "github.com/qiniu/qmgo") {
+					t.Fatalf("postgres-and-mongodb go.mod should contain qmgo dependency")
+				}
+			},
+		},
 		{
 			name:    "mysql-and-mongodb",
 			mysql:   true,
@@ -214,6 +722,52 @@ func testGenerateE2EDBCombos(t *testing.T, runBuildChecks bool) {
 				}
 			},
 		},
+		{
+			name:       "mysql-and-mongodb-without-optional-components",
+			mysql:      true,
+			mongodb:    true,
+			components: []string{},
+			module:     "github.com/test/minimal-web",
+			binary:     "minimal-web",
+			project:    "minimal-web",
+			leakCheck: func(t *testing.T, outputDir string) {
+				t.Helper()
+				goMod := readFileForAssertion(t, filepath.Join(outputDir, "go.mod"))
+				if strings.Contains(goMod, "github.com/robfig/cron/v3") {
+					t.Fatalf("go.mod without cron should not contain cron dependency")
+				}
+				if strings.Contains(goMod, "github.com/larksuite/oapi-sdk-go/v3") {
+					t.Fatalf("go.mod without lark should not contain lark sdk dependency")
+				}
+				if strings.Contains(goMod, "github.com/golang-jwt/jwt/v5") {
+					t.Fatalf("go.mod without jwt should not contain jwt dependency")
+				}
+				if strings.Contains(goMod, "github.com/redis/go-redis/v9") {
+					t.Fatalf("go.mod without redis should not contain go-redis dependency")
+				}
+			},
+		},
+		{
+			name:       "mysql-without-redis",
+			mysql:      true,
+			components: []string{"cron", "lark", "prometheus", "jwt"},
+			module:     "github.com/test/no-redis-web",
+			binary:     "no-redis-web",
+			project:    "no-redis-web",
+			leakCheck: func(t *testing.T, outputDir string) {
+				t.Helper()
+				goMod := readFileForAssertion(t, filepath.Join(outputDir, "go.mod"))
+				if strings.Contains(goMod, "github.com/redis/go-redis/v9") {
+					t.Fatalf("go.mod without redis should not contain go-redis dependency")
+				}
+				if strings.Contains(goMod, "github.com/bsm/redislock") {
+					t.Fatalf("go.mod without redis should not contain redislock dependency")
+				}
+				if !strings.Contains(goMod, "github.com/golang-jwt/jwt/v5") {
+					t.Fatalf("go.mod with jwt should contain jwt dependency")
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -225,7 +779,10 @@ func testGenerateE2EDBCombos(t *testing.T, runBuildChecks bool) {
 				BinaryName:  tt.binary,
 				ProjectName: tt.project,
 				MySQL:       tt.mysql,
+				Postgres:    tt.postgres,
+				SQLite:      tt.sqlite,
 				MongoDB:     tt.mongodb,
+				Components:  tt.components,
 			}
 
 			if err := Generate(outputDir, data); err != nil {
@@ -238,7 +795,7 @@ func testGenerateE2EDBCombos(t *testing.T, runBuildChecks bool) {
 			assertFileExists(t, filepath.Join(outputDir, "internal", "repository", "module.go"))
 			assertFileExists(t, filepath.Join(outputDir, "internal", "controller", "module.go"))
 
-			if tt.mysql {
+			if tt.mysql || tt.postgres || tt.sqlite {
 				assertFileExists(t, filepath.Join(outputDir, "internal", "lib", "gorm", "gorm.go"))
 				assertFileExists(t, filepath.Join(outputDir, "internal", "controller", "example_controller", "user_handler.go"))
 			} else {
@@ -314,6 +871,34 @@ func assertFileNotExists(t *testing.T, path string) {
 	}
 }
 
+func writeTemplateTreeForTest(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
+
+func assertDirEntries(t *testing.T, dir string, want ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir %s: %v", dir, err)
+	}
+	got := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("entries of %s = %v, want %v", dir, got, want)
+	}
+}
+
 func readFileForAssertion(t *testing.T, path string) string {
 	t.Helper()
 	data, err := os.ReadFile(path)
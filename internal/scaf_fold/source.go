@@ -0,0 +1,184 @@
+package scaf_fold
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// deleteMarkerSuffix marks an overlay file that removes the template at the
+// same path without the suffix (with or without .tmpl) from the layers below.
+const deleteMarkerSuffix = ".delete"
+
+// templateSource is a template tree rooted at its top-level directory, plus
+// the prefix used to name its files in error messages.
+type templateSource struct {
+	fsys fs.FS
+	root string
+	// dir is the directory the templates are loaded from, "" for the
+	// built-in templates.
+	dir string
+}
+
+// templateFile is a single template in the merged view of all layers.
+type templateFile struct {
+	path string
+	src  templateSource
+}
+
+func (f templateFile) displayPath() string {
+	return f.src.displayPath(f.path)
+}
+
+// mode returns the permission the file is written with: the mode of rule
+// when it declares one, otherwise 0755 for files executable in the template
+// tree and 0644 for the rest. Embedded templates are never executable.
+func (f templateFile) mode(rule manifestRule) (fs.FileMode, error) {
+	if rule.mode != 0 {
+		return rule.mode, nil
+	}
+	info, err := fs.Stat(f.src.fsys, f.path)
+	if err != nil {
+		return 0, fmt.Errorf("stat template file %s: %w", f.displayPath(), err)
+	}
+	if info.Mode().Perm()&0o111 != 0 {
+		return 0o755, nil
+	}
+	return 0o644, nil
+}
+
+func (s templateSource) displayPath(name string) string {
+	return filepath.Join(s.root, filepath.FromSlash(name))
+}
+
+func openTemplateSource(templateDir string) (templateSource, error) {
+	if templateDir == "" {
+		sub, err := fs.Sub(templateFS, templateRoot)
+		if err != nil {
+			return templateSource{}, fmt.Errorf("open embedded templates: %w", err)
+		}
+		return templateSource{fsys: sub, root: templateRoot}, nil
+	}
+
+	info, err := os.Stat(templateDir)
+	if err != nil {
+		return templateSource{}, fmt.Errorf("open template directory %s: %w", templateDir, err)
+	}
+	if !info.IsDir() {
+		return templateSource{}, fmt.Errorf("template path is not a directory: %s", templateDir)
+	}
+
+	return templateSource{fsys: os.DirFS(templateDir), root: templateDir, dir: templateDir}, nil
+}
+
+// templateSet is the merged view of the base templates and every overlay.
+type templateSet struct {
+	files    []templateFile
+	manifest templateManifest
+}
+
+// loadTemplateSet merges the base templates with every overlay. Files are
+// returned in the same order fs.WalkDir would visit them, and the manifests
+// of all layers are combined with later layers taking precedence.
+func loadTemplateSet(opts Options) (templateSet, error) {
+	base, err := openTemplateSource(opts.TemplateDir)
+	if err != nil {
+		return templateSet{}, err
+	}
+
+	layers := []templateSource{base}
+	for _, overlayDir := range opts.Overlays {
+		if overlayDir == "" {
+			continue
+		}
+		overlay, err := openTemplateSource(overlayDir)
+		if err != nil {
+			return templateSet{}, fmt.Errorf("open overlay: %w", err)
+		}
+		layers = append(layers, overlay)
+	}
+
+	var manifest templateManifest
+	merged := make(map[string]templateFile)
+	// layerOf records which layer provides each merged file, so that a
+	// .delete marker only removes files of lower layers.
+	layerOf := make(map[string]int)
+	for i, layer := range layers {
+		isOverlay := i > 0
+		if err := fs.WalkDir(layer.fsys, ".", func(path string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return fmt.Errorf(
+					"walk template path %s: %w",
+					layer.displayPath(path),
+					walkErr,
+				)
+			}
+			if d.IsDir() {
+				if path != "." && d.Name() == ".git" {
+					return fs.SkipDir
+				}
+				return nil
+			}
+
+			if path == manifestFile {
+				raw, err := fs.ReadFile(layer.fsys, path)
+				if err != nil {
+					return fmt.Errorf("read manifest %s: %w", layer.displayPath(path), err)
+				}
+				rules, err := parseManifest(layer.displayPath(path), raw)
+				if err != nil {
+					return err
+				}
+				manifest.add(rules)
+				return nil
+			}
+
+			if isOverlay && strings.HasSuffix(path, deleteMarkerSuffix) {
+				target := strings.TrimSuffix(path, deleteMarkerSuffix)
+				for existing := range merged {
+					if layerOf[existing] == i {
+						continue
+					}
+					if existing == target ||
+						existing == target+".tmpl" ||
+						strings.HasPrefix(existing, target+"/") {
+						delete(merged, existing)
+					}
+				}
+				return nil
+			}
+
+			merged[path] = templateFile{path: path, src: layer}
+			layerOf[path] = i
+			return nil
+		}); err != nil {
+			return templateSet{}, fmt.Errorf("walk templates: %w", err)
+		}
+	}
+
+	files := make([]templateFile, 0, len(merged))
+	for _, file := range merged {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return lessTemplatePath(files[i].path, files[j].path)
+	})
+
+	return templateSet{files: files, manifest: manifest}, nil
+}
+
+// lessTemplatePath orders slash-separated paths segment by segment, matching
+// the lexical order in which fs.WalkDir visits a tree.
+func lessTemplatePath(a, b string) bool {
+	as := strings.Split(a, "/")
+	bs := strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			return as[i] < bs[i]
+		}
+	}
+	return len(as) < len(bs)
+}
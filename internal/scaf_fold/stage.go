@@ -0,0 +1,185 @@
+package scaf_fold
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeProject stages entries in a temporary sibling of outputDir and moves
+// them into place only after every file has been written, so a failure never
+// leaves a half-populated output directory behind. When outputDir already
+// exists, files that are already there are created, skipped or overwritten
+// according to opts.OnConflict, and the changes are undone if a move fails.
+func writeProject(outputDir string, entries []renderedEntry, opts Options) (result GenerateResult, err error) {
+	existed, err := prepareOutputDir(outputDir)
+	if err != nil {
+		return result, err
+	}
+
+	actions := make(map[string]fileAction, len(entries))
+	var backups map[string]string
+	if existed {
+		if actions, err = resolveConflicts(outputDir, entries, opts); err != nil {
+			return result, err
+		}
+		if backups, err = backupPaths(outputDir, entries, actions); err != nil {
+			return result, err
+		}
+	}
+	staged := make([]renderedEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.isDir {
+			staged = append(staged, entry)
+			continue
+		}
+		action := actions[entry.path]
+		result.record(entry.path, action)
+		if backup, ok := backups[entry.path]; ok {
+			if result.Backups == nil {
+				result.Backups = make(map[string]string)
+			}
+			result.Backups[entry.path] = backup
+		}
+		if action != actionSkip {
+			staged = append(staged, entry)
+		}
+	}
+
+	absOutputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return result, fmt.Errorf("resolve output directory %s: %w", outputDir, err)
+	}
+
+	parentDir := filepath.Dir(absOutputDir)
+	createdParent, err := mkdirAllTracked(parentDir)
+	if err != nil {
+		return result, fmt.Errorf("create output parent directory %s: %w", parentDir, err)
+	}
+	defer func() {
+		if err != nil && createdParent != "" {
+			_ = os.RemoveAll(createdParent)
+		}
+	}()
+
+	stageDir, err := os.MkdirTemp(parentDir, "."+filepath.Base(absOutputDir)+".staging-")
+	if err != nil {
+		return result, fmt.Errorf("create staging directory in %s: %w", parentDir, err)
+	}
+	defer func() {
+		_ = os.RemoveAll(stageDir)
+	}()
+
+	if err := writeEntries(stageDir, staged); err != nil {
+		return result, err
+	}
+
+	if !existed {
+		if err := os.Chmod(stageDir, 0o755); err != nil {
+			return result, fmt.Errorf("set permissions on staging directory %s: %w", stageDir, err)
+		}
+		if err := os.Rename(stageDir, absOutputDir); err != nil {
+			return result, fmt.Errorf("move staging directory into %s: %w", outputDir, err)
+		}
+		return result, nil
+	}
+
+	return result, moveStagedEntries(stageDir, absOutputDir, staged, backups)
+}
+
+// stagedMove records a change made to the output directory by
+// moveStagedEntries so that it can be rolled back.
+type stagedMove struct {
+	target string
+	// backup holds the previous content of an overwritten target; it is
+	// empty when target was created.
+	backup string
+}
+
+// moveStagedEntries moves the staged entries into outputDir one by one,
+// moving the files listed in backups to their backup path first. On failure
+// it removes what it created and restores the overwritten files.
+func moveStagedEntries(stageDir, outputDir string, entries []renderedEntry, backups map[string]string) error {
+	var moves []stagedMove
+	rollback := func(moveErr error) error {
+		for i := len(moves) - 1; i >= 0; i-- {
+			move := moves[i]
+			if err := os.RemoveAll(move.target); err != nil {
+				moveErr = errors.Join(moveErr, fmt.Errorf("roll back %s: %w", move.target, err))
+				continue
+			}
+			if move.backup == "" {
+				continue
+			}
+			if err := os.Rename(move.backup, move.target); err != nil {
+				moveErr = errors.Join(moveErr, fmt.Errorf("restore %s: %w", move.target, err))
+			}
+		}
+		return moveErr
+	}
+
+	for _, entry := range entries {
+		target := filepath.Join(outputDir, filepath.FromSlash(entry.path))
+		if entry.isDir {
+			created, err := mkdirAllTracked(target)
+			if err != nil {
+				return rollback(fmt.Errorf("create directory %s: %w", target, err))
+			}
+			if created != "" {
+				moves = append(moves, stagedMove{target: created})
+			}
+			continue
+		}
+
+		created, err := mkdirAllTracked(filepath.Dir(target))
+		if err != nil {
+			return rollback(fmt.Errorf("create directory for %s: %w", target, err))
+		}
+		if created != "" {
+			moves = append(moves, stagedMove{target: created})
+		}
+
+		move := stagedMove{target: target}
+		if backup, ok := backups[entry.path]; ok {
+			move.backup = filepath.Join(outputDir, filepath.FromSlash(backup))
+			if err := os.Rename(target, move.backup); err != nil {
+				return rollback(fmt.Errorf("back up %s: %w", target, err))
+			}
+		}
+		if err := os.Rename(filepath.Join(stageDir, filepath.FromSlash(entry.path)), target); err != nil {
+			if move.backup != "" {
+				moves = append(moves, move)
+			}
+			return rollback(fmt.Errorf("move %s into %s: %w", entry.path, outputDir, err))
+		}
+		moves = append(moves, move)
+	}
+
+	return nil
+}
+
+// mkdirAllTracked behaves like os.MkdirAll and returns the topmost directory
+// it had to create, or "" when dir already existed.
+func mkdirAllTracked(dir string) (string, error) {
+	created := ""
+	for current := dir; ; current = filepath.Dir(current) {
+		if _, err := os.Stat(current); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		created = current
+		if filepath.Dir(current) == current {
+			break
+		}
+	}
+
+	if created == "" {
+		return "", nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return created, nil
+}
@@ -4,22 +4,56 @@ import (
 	"bytes"
 	"embed"
 	"fmt"
+	"strings"
 	"text/template"
 )
 
 //go:embed all:_template
 var templateFS embed.FS
 
-func renderTemplate(filePath string, raw []byte, data TemplateData) ([]byte, error) {
-	tmpl, err := template.New(filePath).Option("missingkey=error").Parse(string(raw))
+func renderTemplate(filePath string, raw []byte, data any) ([]byte, error) {
+	tmpl, err := template.New(filePath).
+		Option("missingkey=error").
+		Funcs(templateFuncs()).
+		Parse(string(raw))
 	if err != nil {
 		return nil, fmt.Errorf("parse template %s: %w", filePath, err)
 	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
+		if extraErr := explainMissingExtra(filePath, err); extraErr != nil {
+			return nil, extraErr
+		}
 		return nil, fmt.Errorf("execute template %s: %w", filePath, err)
 	}
 
 	return buf.Bytes(), nil
 }
+
+// renderPath renders the template expressions in each segment of the
+// slash-separated relPath, so that {{ .BinaryName }}/main.go names its
+// directory after the binary. Segments that render to an empty name, "." or
+// "..", or that contain a path separator are rejected.
+func renderPath(filePath, relPath string, data any) (string, error) {
+	if !strings.Contains(relPath, "{{") {
+		return relPath, nil
+	}
+
+	segments := strings.Split(relPath, "/")
+	for i, segment := range segments {
+		if !strings.Contains(segment, "{{") {
+			continue
+		}
+		rendered, err := renderTemplate(filePath, []byte(segment), data)
+		if err != nil {
+			return "", fmt.Errorf("render path %s: %w", relPath, err)
+		}
+		name := string(rendered)
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return "", fmt.Errorf("render path %s: segment %q renders to unsafe name %q", relPath, segment, name)
+		}
+		segments[i] = name
+	}
+	return strings.Join(segments, "/"), nil
+}
@@ -0,0 +1,333 @@
+package scaf_fold
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SisyphusSQ/go-web-starter/vars"
+)
+
+// baseSnapshotDir keeps, below the project root, the rendered content of the
+// files whose working copy differs from the last render, so that the next
+// upgrade can use it as the merge base.
+const baseSnapshotDir = ".go-web-starter/base"
+
+const gitLookupTimeout = 30 * time.Second
+
+// UpgradeResult reports what Upgrade did to each file, by slash-separated
+// path relative to the project root.
+type UpgradeResult struct {
+	FromVersion string
+	ToVersion   string
+	// Updated files were pristine and now hold the new render.
+	Updated []string
+	// Added files are new in the templates.
+	Added []string
+	// Removed files were pristine and are no longer rendered.
+	Removed []string
+	// Merged files combine local and template changes without conflicts.
+	Merged []string
+	// Conflicts files contain conflict markers around overlapping changes.
+	Conflicts []string
+	// Rejected files have no known merge base; the new render is left next
+	// to them as a <path>.rej unified diff against /dev/null.
+	Rejected []string
+	// Skipped files changed in the templates but were deleted locally, were
+	// modified locally and are no longer rendered, or come from a template
+	// directory that is not part of this render.
+	Skipped []string
+}
+
+// NeedsResolution reports whether conflict markers or .rej files were left
+// for manual resolution.
+func (r UpgradeResult) NeedsResolution() bool {
+	return len(r.Conflicts) > 0 || len(r.Rejected) > 0
+}
+
+// Upgrade re-renders the templates with the options recorded in the
+// project's lockfile and applies the template changes since the recorded
+// render to the project at projectDir. Files that were not modified locally
+// are replaced, modified files are three-way merged with the recorded render
+// as the base, and the lockfile is rewritten for the new render.
+//
+// The template directory and overlays of opts default to the recorded ones.
+// Files rendered from a directory that opts leaves out are kept as they are.
+// Upgrade refuses to run while .rej files of a previous upgrade are left.
+func Upgrade(projectDir string, opts Options) (UpgradeResult, error) {
+	lock, err := ReadLockfile(projectDir)
+	if err != nil {
+		return UpgradeResult{}, err
+	}
+	if err := checkPendingRejects(projectDir, lock); err != nil {
+		return UpgradeResult{}, err
+	}
+	opts = lock.templateOptions(projectDir, opts)
+	entries, err := renderLocked(lock, opts)
+	if err != nil {
+		return UpgradeResult{}, err
+	}
+
+	result := UpgradeResult{FromVersion: lock.StarterVersion, ToVersion: vars.AppVersion}
+	snapshots := make(map[string][]byte)
+	rendered := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.isDir {
+			continue
+		}
+		rendered[entry.path] = true
+		if err := upgradeFile(projectDir, lock, entry, &result, snapshots); err != nil {
+			return result, err
+		}
+	}
+
+	newLock := newLockfile(projectDir, lock.Data, opts, entries)
+	layers := renderedLayers(opts)
+	for _, relPath := range sortedKeys(lock.Files) {
+		if rendered[relPath] {
+			continue
+		}
+		outPath := filepath.Join(projectDir, filepath.FromSlash(relPath))
+		if !layers[lock.origin(projectDir, relPath)] {
+			// Keep the file and its record until it is rendered with its
+			// template directory again.
+			newLock.Files[relPath] = lock.Files[relPath]
+			if origin, ok := lock.Origins[relPath]; ok {
+				if newLock.Origins == nil {
+					newLock.Origins = make(map[string]string)
+				}
+				newLock.Origins[relPath] = origin
+			}
+			if base, found := readBaseSnapshot(projectDir, relPath, lock.Files[relPath]); found {
+				snapshots[relPath] = base
+			}
+			if _, err := os.Stat(outPath); err == nil {
+				result.Skipped = append(result.Skipped, relPath)
+			}
+			continue
+		}
+		pristine, err := lock.Pristine(projectDir, relPath)
+		if err != nil {
+			return result, err
+		}
+		if !pristine {
+			if _, err := os.Stat(outPath); err == nil {
+				result.Skipped = append(result.Skipped, relPath)
+			}
+			continue
+		}
+		if err := os.Remove(outPath); err != nil {
+			return result, fmt.Errorf("remove %s: %w", outPath, err)
+		}
+		result.Removed = append(result.Removed, relPath)
+	}
+
+	if err := writeBaseSnapshots(projectDir, snapshots); err != nil {
+		return result, err
+	}
+	if err := writeLockfile(projectDir, newLock); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// checkPendingRejects fails when a file recorded in lock still has the
+// <path>.rej of a previous upgrade next to it. The lockfile already records
+// the rejected render as the base of that file, so upgrading again before the
+// .rej is merged would revert the upstream change it holds.
+func checkPendingRejects(projectDir string, lock Lockfile) error {
+	var pending []string
+	for _, relPath := range sortedKeys(lock.Files) {
+		found, err := regularFileExists(filepath.Join(projectDir, filepath.FromSlash(relPath)+".rej"))
+		if err != nil {
+			return err
+		}
+		if found {
+			pending = append(pending, relPath+".rej")
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf(
+			"pending .rej files from a previous upgrade: %s; merge them by hand and delete them first",
+			strings.Join(pending, ", "),
+		)
+	}
+	return nil
+}
+
+// renderLocked renders the templates with the template data of lock.
+func renderLocked(lock Lockfile, opts Options) ([]renderedEntry, error) {
+	data := lock.Data
+	data.applyDefaults()
+	if err := data.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid template data in lockfile: %w", err)
+	}
+	return renderProject(data, opts)
+}
+
+func upgradeFile(
+	projectDir string,
+	lock Lockfile,
+	entry renderedEntry,
+	result *UpgradeResult,
+	snapshots map[string][]byte,
+) error {
+	relPath := entry.path
+	outPath := filepath.Join(projectDir, filepath.FromSlash(relPath))
+	lockedHash, locked := lock.Files[relPath]
+	renderedHash := HashContent(entry.content)
+
+	current, err := os.ReadFile(outPath)
+	if os.IsNotExist(err) {
+		switch {
+		case !locked:
+			if err := writeUpgradedFile(outPath, entry.content, entry.perm()); err != nil {
+				return err
+			}
+			result.Added = append(result.Added, relPath)
+		case lockedHash != renderedHash:
+			result.Skipped = append(result.Skipped, relPath)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", outPath, err)
+	}
+
+	currentHash := HashContent(current)
+	switch {
+	case currentHash == renderedHash:
+		return nil
+	case locked && lockedHash == renderedHash:
+		// Unchanged in the templates: keep the local version and its base.
+		snapshots[relPath] = entry.content
+		return nil
+	case locked && currentHash == lockedHash:
+		if err := writeUpgradedFile(outPath, entry.content, entry.perm()); err != nil {
+			return err
+		}
+		result.Updated = append(result.Updated, relPath)
+		return nil
+	}
+
+	var base []byte
+	if locked {
+		var found bool
+		if base, found = findMergeBase(projectDir, relPath, lockedHash); !found {
+			// Without the previous render the upstream change is unknown, and
+			// a diff from the local file would revert local edits; the .rej
+			// adds the whole new render instead, to be merged by hand.
+			rejPath := outPath + ".rej"
+			diff := unifiedDiff("/dev/null", "b/"+relPath, nil, entry.content)
+			if err := os.WriteFile(rejPath, []byte(diff), 0o644); err != nil {
+				return fmt.Errorf("write %s: %w", rejPath, err)
+			}
+			snapshots[relPath] = entry.content
+			result.Rejected = append(result.Rejected, relPath)
+			return nil
+		}
+	}
+
+	merged, conflicts := merge3(base, current, entry.content, "local", vars.AppName+" "+vars.AppVersion)
+	if err := writeUpgradedFile(outPath, merged, entry.perm()); err != nil {
+		return err
+	}
+	if HashContent(merged) != renderedHash {
+		snapshots[relPath] = entry.content
+	}
+	if conflicts > 0 {
+		result.Conflicts = append(result.Conflicts, relPath)
+	} else {
+		result.Merged = append(result.Merged, relPath)
+	}
+	return nil
+}
+
+// findMergeBase returns the content recorded in the lockfile for relPath,
+// looking in the base snapshots and then in the git history of the project.
+func findMergeBase(projectDir, relPath, hash string) ([]byte, bool) {
+	if snapshot, found := readBaseSnapshot(projectDir, relPath, hash); found {
+		return snapshot, true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitLookupTimeout)
+	defer cancel()
+
+	revs, err := gitOutput(ctx, projectDir, "log", "--format=%H", "--", relPath)
+	if err != nil {
+		return nil, false
+	}
+	for _, rev := range strings.Fields(string(revs)) {
+		content, err := gitOutput(ctx, projectDir, "show", rev+":./"+relPath)
+		if err == nil && HashContent(content) == hash {
+			return content, true
+		}
+	}
+	return nil, false
+}
+
+// readBaseSnapshot returns the base snapshot of relPath when it has the
+// given hash.
+func readBaseSnapshot(projectDir, relPath, hash string) ([]byte, bool) {
+	snapshot, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(baseSnapshotDir), filepath.FromSlash(relPath)))
+	if err == nil && HashContent(snapshot) == hash {
+		return snapshot, true
+	}
+	return nil, false
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// writeUpgradedFile writes content to an existing file keeping its mode, or
+// creates it with perm.
+func writeUpgradedFile(outPath string, content []byte, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return fmt.Errorf("create directory for %s: %w", outPath, err)
+	}
+	if err := os.WriteFile(outPath, content, perm); err != nil {
+		return fmt.Errorf("write %s: %w", outPath, err)
+	}
+	return nil
+}
+
+// writeBaseSnapshots replaces the base snapshots of the project with
+// snapshots.
+func writeBaseSnapshots(projectDir string, snapshots map[string][]byte) error {
+	dir := filepath.Join(projectDir, filepath.FromSlash(baseSnapshotDir))
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("remove base snapshots %s: %w", dir, err)
+	}
+	for relPath, content := range snapshots {
+		if err := writeUpgradedFile(filepath.Join(dir, filepath.FromSlash(relPath)), content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeLockfile(projectDir string, lock Lockfile) error {
+	content, err := encodeLockfile(lock)
+	if err != nil {
+		return err
+	}
+	outPath := filepath.Join(projectDir, LockfileName)
+	if err := os.WriteFile(outPath, content, 0o644); err != nil {
+		return fmt.Errorf("write lockfile %s: %w", outPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package scaf_fold
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const upgradeBaseMakefile = "build:\n\tgo build\n\ntest:\n\tgo test\n\nlint:\n\tgo vet\n"
+
+func TestUpgrade(t *testing.T) {
+	projectDir := generateUpgradeProjectForTest(t, map[string]string{
+		"Makefile":       upgradeBaseMakefile,
+		"README.md.tmpl": "# {{ .ProjectName }}\n",
+		"LICENSE":        "MIT\n",
+		"old.txt":        "old\n",
+		"kept.txt":       "kept\n",
+	})
+	writeFileForTest(t, filepath.Join(projectDir, "kept.txt"), "kept locally\n")
+
+	result := upgradeForTest(t, projectDir, map[string]string{
+		"Makefile":       upgradeBaseMakefile,
+		"README.md.tmpl": "# {{ .ProjectName }}\n\nUpgraded.\n",
+		"LICENSE":        "MIT\n",
+		"new.txt":        "new\n",
+	})
+
+	want := UpgradeResult{
+		FromVersion: result.FromVersion,
+		ToVersion:   result.ToVersion,
+		Updated:     []string{"README.md"},
+		Added:       []string{"new.txt"},
+		Removed:     []string{"old.txt"},
+		Skipped:     []string{"kept.txt"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Fatalf("Upgrade() = %+v, want %+v", result, want)
+	}
+	if got := readFileForAssertion(t, filepath.Join(projectDir, "README.md")); got != "# upgrade-web\n\nUpgraded.\n" {
+		t.Fatalf("README.md = %q", got)
+	}
+	assertFileNotExists(t, filepath.Join(projectDir, "old.txt"))
+	assertFileExists(t, filepath.Join(projectDir, "kept.txt"))
+
+	lock, err := ReadLockfile(projectDir)
+	if err != nil {
+		t.Fatalf("ReadLockfile() error = %v", err)
+	}
+	if got := sortedKeys(lock.Files); !reflect.DeepEqual(got, []string{"LICENSE", "Makefile", "README.md", "new.txt"}) {
+		t.Fatalf("lockfile files after upgrade = %v", got)
+	}
+	for relPath := range lock.Files {
+		if pristine, err := lock.Pristine(projectDir, relPath); err != nil || !pristine {
+			t.Errorf("Pristine(%s) = %v, %v after upgrade", relPath, pristine, err)
+		}
+	}
+}
+
+func TestUpgradeRejectsWithoutBase(t *testing.T) {
+	projectDir := generateUpgradeProjectForTest(t, map[string]string{"Makefile": upgradeBaseMakefile})
+	local := strings.Replace(upgradeBaseMakefile, "go build", "go build -v", 1)
+	writeFileForTest(t, filepath.Join(projectDir, "Makefile"), local)
+
+	result := upgradeForTest(t, projectDir, map[string]string{
+		"Makefile": strings.Replace(upgradeBaseMakefile, "go vet", "golangci-lint run", 1),
+	})
+	if !reflect.DeepEqual(result.Rejected, []string{"Makefile"}) || !result.NeedsResolution() {
+		t.Fatalf("Upgrade() = %+v, want Makefile rejected", result)
+	}
+	if got := readFileForAssertion(t, filepath.Join(projectDir, "Makefile")); got != local {
+		t.Fatalf("rejected Makefile should keep its local content, got %q", got)
+	}
+	rej := readFileForAssertion(t, filepath.Join(projectDir, "Makefile.rej"))
+	for _, want := range []string{"--- /dev/null\n+++ b/Makefile\n", "+\tgo build\n", "+\tgolangci-lint run\n"} {
+		if !strings.Contains(rej, want) {
+			t.Fatalf("Makefile.rej missing %q:\n%s", want, rej)
+		}
+	}
+	// The local edit is not part of the upstream change and must not be
+	// reverted by the .rej.
+	if strings.Contains(rej, "go build -v") {
+		t.Fatalf("Makefile.rej should not revert local changes:\n%s", rej)
+	}
+
+	// Upgrading again before the .rej is merged would take the rejected
+	// render as the base and revert its upstream change.
+	if _, err := Upgrade(projectDir, Options{}); err == nil || !strings.Contains(err.Error(), "pending .rej files") ||
+		!strings.Contains(err.Error(), "Makefile.rej") {
+		t.Fatalf("second Upgrade() error = %v, want pending .rej", err)
+	}
+	if _, err := Diff(projectDir, Options{}); err == nil || !strings.Contains(err.Error(), "pending .rej files") {
+		t.Fatalf("Diff() error = %v, want pending .rej", err)
+	}
+
+	resolved := strings.Replace(local, "go vet", "golangci-lint run", 1)
+	writeFileForTest(t, filepath.Join(projectDir, "Makefile"), resolved)
+	if err := os.Remove(filepath.Join(projectDir, "Makefile.rej")); err != nil {
+		t.Fatalf("remove Makefile.rej: %v", err)
+	}
+	v3 := strings.Replace(upgradeBaseMakefile, "go vet", "golangci-lint run", 1)
+	v3 = strings.Replace(v3, "go test", "go test -race", 1)
+	result = upgradeForTest(t, projectDir, map[string]string{"Makefile": v3})
+	if !reflect.DeepEqual(result.Merged, []string{"Makefile"}) || result.NeedsResolution() {
+		t.Fatalf("Upgrade() after resolving = %+v, want Makefile merged", result)
+	}
+	want := "build:\n\tgo build -v\n\ntest:\n\tgo test -race\n\nlint:\n\tgolangci-lint run\n"
+	if got := readFileForAssertion(t, filepath.Join(projectDir, "Makefile")); got != want {
+		t.Fatalf("Makefile after resolving and upgrading = %q, want %q", got, want)
+	}
+}
+
+func TestUpgradeMergesWithGitHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	projectDir := generateUpgradeProjectForTest(t, map[string]string{"Makefile": upgradeBaseMakefile})
+	runGitForTest(t, projectDir, "init", "-q")
+	runGitForTest(t, projectDir, "add", "-A")
+	runGitForTest(t, projectDir, "commit", "-q", "-m", "generate")
+	writeFileForTest(t, filepath.Join(projectDir, "Makefile"), strings.Replace(upgradeBaseMakefile, "go build", "go build -v", 1))
+	runGitForTest(t, projectDir, "commit", "-q", "-am", "verbose build")
+
+	v2 := strings.Replace(upgradeBaseMakefile, "go vet", "golangci-lint run", 1)
+	result := upgradeForTest(t, projectDir, map[string]string{"Makefile": v2})
+	if !reflect.DeepEqual(result.Merged, []string{"Makefile"}) || result.NeedsResolution() {
+		t.Fatalf("Upgrade() = %+v, want Makefile merged", result)
+	}
+	wantMerged := "build:\n\tgo build -v\n\ntest:\n\tgo test\n\nlint:\n\tgolangci-lint run\n"
+	if got := readFileForAssertion(t, filepath.Join(projectDir, "Makefile")); got != wantMerged {
+		t.Fatalf("merged Makefile = %q, want %q", got, wantMerged)
+	}
+
+	// The merged file never existed in git, so the next upgrade has to use
+	// the base snapshot of the v2 render.
+	v3 := strings.Replace(v2, "go test", "go test -race", 1)
+	result = upgradeForTest(t, projectDir, map[string]string{"Makefile": v3})
+	if !reflect.DeepEqual(result.Merged, []string{"Makefile"}) {
+		t.Fatalf("second Upgrade() = %+v, want Makefile merged", result)
+	}
+	wantMerged = "build:\n\tgo build -v\n\ntest:\n\tgo test -race\n\nlint:\n\tgolangci-lint run\n"
+	if got := readFileForAssertion(t, filepath.Join(projectDir, "Makefile")); got != wantMerged {
+		t.Fatalf("merged Makefile = %q, want %q", got, wantMerged)
+	}
+
+	v4 := strings.Replace(v3, "go build", "go build -trimpath", 1)
+	result = upgradeForTest(t, projectDir, map[string]string{"Makefile": v4})
+	if !reflect.DeepEqual(result.Conflicts, []string{"Makefile"}) {
+		t.Fatalf("third Upgrade() = %+v, want Makefile conflict", result)
+	}
+	got := readFileForAssertion(t, filepath.Join(projectDir, "Makefile"))
+	if !strings.Contains(got, "<<<<<<< local\n\tgo build -v\n=======\n\tgo build -trimpath\n>>>>>>> ") {
+		t.Fatalf("conflicted Makefile = %q", got)
+	}
+}
+
+func TestUpgradeUsesRecordedOverlays(t *testing.T) {
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, map[string]string{"Makefile": upgradeBaseMakefile})
+	overlayDir := t.TempDir()
+	writeTemplateTreeForTest(t, overlayDir, map[string]string{"deploy.sh": "v1\n", "ci.yml": "ci\n"})
+
+	projectDir := filepath.Join(t.TempDir(), "overlay-web")
+	if _, err := GenerateWithOptions(projectDir, TemplateData{
+		ModuleName:  "github.com/test/overlay-web",
+		BinaryName:  "overlay-web",
+		ProjectName: "overlay-web",
+		MySQL:       true,
+	}, Options{TemplateDir: templateDir, Overlays: []string{overlayDir}}); err != nil {
+		t.Fatalf("GenerateWithOptions() error = %v", err)
+	}
+	lock, err := ReadLockfile(projectDir)
+	if err != nil {
+		t.Fatalf("ReadLockfile() error = %v", err)
+	}
+	if filepath.IsAbs(lock.TemplateDir) || len(lock.Overlays) != 1 || lock.Origins["deploy.sh"] != lock.Overlays[0] {
+		t.Fatalf("lockfile templates = %q, %q, origins %v", lock.TemplateDir, lock.Overlays, lock.Origins)
+	}
+
+	writeTemplateTreeForTest(t, overlayDir, map[string]string{"deploy.sh": "v2\n"})
+	result, err := Upgrade(projectDir, Options{})
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Updated, []string{"deploy.sh"}) {
+		t.Fatalf("Upgrade() = %+v, want deploy.sh updated from the recorded overlay", result)
+	}
+
+	// Upgrading without the overlay must not delete its files.
+	result, err = Upgrade(projectDir, Options{TemplateDir: templateDir})
+	if err != nil {
+		t.Fatalf("Upgrade() without overlay error = %v", err)
+	}
+	if len(result.Removed) != 0 || !reflect.DeepEqual(result.Skipped, []string{"ci.yml", "deploy.sh"}) {
+		t.Fatalf("Upgrade() without overlay = %+v, want overlay files skipped", result)
+	}
+	assertFileExists(t, filepath.Join(projectDir, "deploy.sh"))
+	if lock, err = ReadLockfile(projectDir); err != nil {
+		t.Fatalf("ReadLockfile() error = %v", err)
+	}
+	if _, ok := lock.Files["deploy.sh"]; !ok || lock.Origins["deploy.sh"] == "" {
+		t.Fatalf("lockfile should keep tracking deploy.sh: %+v", lock)
+	}
+}
+
+func TestUpgradeRequiresLockfile(t *testing.T) {
+	if _, err := Upgrade(t.TempDir(), Options{}); err == nil || !strings.Contains(err.Error(), "read lockfile") {
+		t.Fatalf("Upgrade() error = %v, want missing lockfile error", err)
+	}
+}
+
+func generateUpgradeProjectForTest(t *testing.T, files map[string]string) string {
+	t.Helper()
+	templateDir := t.TempDir()
+	writeTemplateTreeForTest(t, templateDir, files)
+
+	projectDir := filepath.Join(t.TempDir(), "upgrade-web")
+	if _, err := GenerateWithOptions(projectDir, TemplateData{
+		ModuleName:  "github.com/test/upgrade-web",
+		BinaryName:  "upgrade-web",
+		ProjectName: "upgrade-web",
+		MySQL:       true,
+	}, Options{TemplateDir: templateDir}); err != nil {
+		t.Fatalf("GenerateWithOptions() error = %v", err)
+	}
+	return projectDir
+}
+
+// upgradeForTest replaces the content of the template directory recorded in
+// the lockfile with files and upgrades the project from it.
+func upgradeForTest(t *testing.T, projectDir string, files map[string]string) UpgradeResult {
+	t.Helper()
+	lock, err := ReadLockfile(projectDir)
+	if err != nil {
+		t.Fatalf("ReadLockfile() error = %v", err)
+	}
+	templateDir := resolveRecordedDir(projectDir, lock.TemplateDir)
+	if err := os.RemoveAll(templateDir); err != nil {
+		t.Fatalf("remove %s: %v", templateDir, err)
+	}
+	writeTemplateTreeForTest(t, templateDir, files)
+
+	result, err := Upgrade(projectDir, Options{})
+	if err != nil {
+		t.Fatalf("Upgrade() error = %v", err)
+	}
+	return result
+}
+
+func runGitForTest(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func writeFileForTest(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
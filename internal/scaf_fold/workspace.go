@@ -0,0 +1,326 @@
+package scaf_fold
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// Workspace describes the go.mod and go.work that enclose a directory, found
+// the way the go command finds them: in the directory or the nearest parent.
+type Workspace struct {
+	// ModFile is the path of the enclosing go.mod, or "" when there is none.
+	ModFile string
+	// ModulePath is the module path declared in ModFile.
+	ModulePath string
+	// WorkFile is the path of the enclosing go.work, or "" when there is none
+	// or GOWORK=off.
+	WorkFile string
+}
+
+// FindWorkspace looks for the go.mod and go.work enclosing dir, which does
+// not need to exist yet. A GOWORK environment variable overrides the go.work
+// lookup like it does for the go command.
+func FindWorkspace(dir string) (Workspace, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return Workspace{}, fmt.Errorf("resolve directory %s: %w", dir, err)
+	}
+
+	var ws Workspace
+	gowork := os.Getenv("GOWORK")
+	lookupWork := gowork == "" || gowork == "auto"
+	if !lookupWork && gowork != "off" {
+		if ws.WorkFile, err = filepath.Abs(gowork); err != nil {
+			return Workspace{}, fmt.Errorf("resolve GOWORK %s: %w", gowork, err)
+		}
+	}
+
+	for current := absDir; ; current = filepath.Dir(current) {
+		if ws.ModFile == "" {
+			if found, err := regularFileExists(filepath.Join(current, "go.mod")); err != nil {
+				return Workspace{}, err
+			} else if found {
+				ws.ModFile = filepath.Join(current, "go.mod")
+			}
+		}
+		if lookupWork && ws.WorkFile == "" {
+			if found, err := regularFileExists(filepath.Join(current, "go.work")); err != nil {
+				return Workspace{}, err
+			} else if found {
+				ws.WorkFile = filepath.Join(current, "go.work")
+			}
+		}
+		if filepath.Dir(current) == current {
+			break
+		}
+	}
+
+	if ws.ModFile != "" {
+		raw, err := os.ReadFile(ws.ModFile)
+		if err != nil {
+			return Workspace{}, fmt.Errorf("read %s: %w", ws.ModFile, err)
+		}
+		ws.ModulePath = modfile.ModulePath(raw)
+		if ws.ModulePath == "" {
+			return Workspace{}, fmt.Errorf("%s has no module directive", ws.ModFile)
+		}
+	}
+	return ws, nil
+}
+
+func regularFileExists(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("stat %s: %w", path, err)
+	}
+	return !info.IsDir(), nil
+}
+
+// ImportPath returns the import path of dir inside the enclosing module and
+// dir relative to the module root as a slash-separated path ("." for the
+// root itself).
+func (w Workspace) ImportPath(dir string) (string, string, error) {
+	if w.ModFile == "" {
+		return "", "", fmt.Errorf("no go.mod found in %s or its parent directories", dir)
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("resolve directory %s: %w", dir, err)
+	}
+	rel, err := filepath.Rel(filepath.Dir(w.ModFile), absDir)
+	if err != nil {
+		return "", "", fmt.Errorf("resolve %s inside module %s: %w", dir, w.ModulePath, err)
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		return w.ModulePath, rel, nil
+	}
+	return w.ModulePath + "/" + rel, rel, nil
+}
+
+// WorkUses reports whether the go.work file at workFile already has a use
+// directive for the module at moduleDir.
+func WorkUses(workFile, moduleDir string) (bool, error) {
+	work, err := readWorkFile(workFile)
+	if err != nil {
+		return false, err
+	}
+	return workUsesDir(work, workFile, moduleDir)
+}
+
+// AddWorkUse adds a use directive for the module at moduleDir to the go.work
+// file at workFile and, like go work use, raises the go version of the
+// workspace when the module needs a newer one. It reports false when the
+// go.work already uses moduleDir.
+func AddWorkUse(workFile, moduleDir string) (bool, error) {
+	goModPath := filepath.Join(moduleDir, "go.mod")
+	rawMod, err := os.ReadFile(goModPath)
+	if err != nil {
+		return false, fmt.Errorf("read %s: %w", goModPath, err)
+	}
+	mod, err := modfile.ParseLax(goModPath, rawMod, nil)
+	if err != nil {
+		return false, fmt.Errorf("parse %s: %w", goModPath, err)
+	}
+	if mod.Module == nil {
+		return false, fmt.Errorf("%s has no module directive", goModPath)
+	}
+
+	work, err := readWorkFile(workFile)
+	if err != nil {
+		return false, err
+	}
+	if uses, err := workUsesDir(work, workFile, moduleDir); err != nil || uses {
+		return false, err
+	}
+
+	absModuleDir, err := filepath.Abs(moduleDir)
+	if err != nil {
+		return false, fmt.Errorf("resolve directory %s: %w", moduleDir, err)
+	}
+	rel, err := filepath.Rel(filepath.Dir(workFile), absModuleDir)
+	if err != nil {
+		return false, fmt.Errorf("resolve %s relative to %s: %w", moduleDir, workFile, err)
+	}
+	diskPath := filepath.ToSlash(rel)
+	if diskPath != "." && !strings.HasPrefix(diskPath, "../") {
+		diskPath = "./" + diskPath
+	}
+	if err := work.AddUse(diskPath, mod.Module.Mod.Path); err != nil {
+		return false, fmt.Errorf("add use %s to %s: %w", diskPath, workFile, err)
+	}
+	if work.Go != nil && mod.Go != nil && goVersionLess(work.Go.Version, mod.Go.Version) {
+		if err := work.AddGoStmt(mod.Go.Version); err != nil {
+			return false, fmt.Errorf("set go %s in %s: %w", mod.Go.Version, workFile, err)
+		}
+	}
+	work.Cleanup()
+
+	if err := os.WriteFile(workFile, modfile.Format(work.Syntax), 0o644); err != nil {
+		return false, fmt.Errorf("write %s: %w", workFile, err)
+	}
+	return true, nil
+}
+
+func readWorkFile(workFile string) (*modfile.WorkFile, error) {
+	raw, err := os.ReadFile(workFile)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", workFile, err)
+	}
+	work, err := modfile.ParseWork(workFile, raw, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", workFile, err)
+	}
+	return work, nil
+}
+
+// workUsesDir reports whether a use directive of work, read from workFile,
+// points at moduleDir.
+func workUsesDir(work *modfile.WorkFile, workFile, moduleDir string) (bool, error) {
+	absModuleDir, err := filepath.Abs(moduleDir)
+	if err != nil {
+		return false, fmt.Errorf("resolve directory %s: %w", moduleDir, err)
+	}
+	workDir := filepath.Dir(workFile)
+	for _, use := range work.Use {
+		usePath := filepath.FromSlash(use.Path)
+		if !filepath.IsAbs(usePath) {
+			usePath = filepath.Join(workDir, usePath)
+		}
+		if filepath.Clean(usePath) == absModuleDir {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AddModuleRequirements adds the requirements of the go.mod that data would
+// render as a standalone module to the enclosing go.mod at modFile, so that
+// a no-module project builds with the versions the templates were written
+// for, and raises its go version when the templates need a newer one.
+// Existing requirements are only upgraded. It returns the "path version"
+// requirements it added or upgraded.
+func AddModuleRequirements(modFile string, data TemplateData, opts Options) ([]string, error) {
+	mod, changed, goChanged, err := moduleRequirements(modFile, data, opts)
+	if err != nil || (len(changed) == 0 && !goChanged) {
+		return nil, err
+	}
+
+	mod.Cleanup()
+	content, err := mod.Format()
+	if err != nil {
+		return nil, fmt.Errorf("format %s: %w", modFile, err)
+	}
+	if err := os.WriteFile(modFile, content, 0o644); err != nil {
+		return nil, fmt.Errorf("write %s: %w", modFile, err)
+	}
+	return changed, nil
+}
+
+// PlanModuleRequirements returns the requirements AddModuleRequirements
+// would add or upgrade in modFile, without writing it.
+func PlanModuleRequirements(modFile string, data TemplateData, opts Options) ([]string, error) {
+	_, changed, _, err := moduleRequirements(modFile, data, opts)
+	return changed, err
+}
+
+// moduleRequirements applies the requirements and go version of the go.mod
+// that data would render to the parsed go.mod at modFile. It returns the
+// requirements it changed and whether it raised the go version.
+func moduleRequirements(modFile string, data TemplateData, opts Options) (*modfile.File, []string, bool, error) {
+	data.NoModule = false
+	data.ModuleSubdir = ""
+	data.applyDefaults()
+	entries, err := renderProject(data, opts)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	var rendered *modfile.File
+	for _, entry := range entries {
+		if entry.path == "go.mod" {
+			if rendered, err = modfile.ParseLax("go.mod", entry.content, nil); err != nil {
+				return nil, nil, false, fmt.Errorf("parse rendered go.mod: %w", err)
+			}
+		}
+	}
+	if rendered == nil {
+		return nil, nil, false, nil
+	}
+
+	raw, err := os.ReadFile(modFile)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("read %s: %w", modFile, err)
+	}
+	mod, err := modfile.Parse(modFile, raw, nil)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("parse %s: %w", modFile, err)
+	}
+
+	current := make(map[string]string, len(mod.Require))
+	for _, req := range mod.Require {
+		current[req.Mod.Path] = req.Mod.Version
+	}
+	var changed []string
+	for _, req := range rendered.Require {
+		version, ok := current[req.Mod.Path]
+		if ok && semver.Compare(version, req.Mod.Version) >= 0 {
+			continue
+		}
+		if ok {
+			err = mod.AddRequire(req.Mod.Path, req.Mod.Version)
+		} else {
+			mod.AddNewRequire(req.Mod.Path, req.Mod.Version, req.Indirect)
+		}
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("require %s %s in %s: %w", req.Mod.Path, req.Mod.Version, modFile, err)
+		}
+		changed = append(changed, req.Mod.Path+" "+req.Mod.Version)
+	}
+	goChanged := mod.Go != nil && rendered.Go != nil && goVersionLess(mod.Go.Version, rendered.Go.Version)
+	if goChanged {
+		if err := mod.AddGoStmt(rendered.Go.Version); err != nil {
+			return nil, nil, false, fmt.Errorf("set go %s in %s: %w", rendered.Go.Version, modFile, err)
+		}
+	}
+	return mod, changed, goChanged, nil
+}
+
+// goVersionLess compares Go versions such as 1.22 and 1.26.0; versions it
+// cannot parse compare as equal.
+func goVersionLess(a, b string) bool {
+	va, vb := "v"+a, "v"+b
+	if !semver.IsValid(va) || !semver.IsValid(vb) {
+		return false
+	}
+	return semver.Compare(va, vb) < 0
+}
+
+func validateModuleSubdir(noModule bool, subdir string) error {
+	if !noModule {
+		if subdir != "" {
+			return fmt.Errorf("module subdirectory %q is only used with no-module projects", subdir)
+		}
+		return nil
+	}
+	if subdir == "." {
+		return fmt.Errorf("module subdirectory %q is the module root: a no-module project must be a directory below it", subdir)
+	}
+	if subdir == "" ||
+		path.IsAbs(subdir) ||
+		path.Clean(subdir) != subdir ||
+		subdir == ".." ||
+		strings.HasPrefix(subdir, "../") ||
+		strings.Contains(subdir, `\`) {
+		return fmt.Errorf("invalid module subdirectory %q: must be a clean relative slash-separated path inside the module", subdir)
+	}
+	return nil
+}
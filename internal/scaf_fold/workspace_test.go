@@ -0,0 +1,242 @@
+package scaf_fold
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindWorkspace(t *testing.T) {
+	t.Setenv("GOWORK", "")
+	root := t.TempDir()
+	writeTemplateTreeForTest(t, root, map[string]string{
+		"go.work":           "go 1.22\n\nuse .\n",
+		"go.mod":            "module example.com/mono\n\ngo 1.22\n",
+		"services/.keep":    "",
+		"tools/lint/go.mod": "module example.com/mono/tools/lint\n",
+	})
+
+	ws, err := FindWorkspace(filepath.Join(root, "services", "billing"))
+	if err != nil {
+		t.Fatalf("FindWorkspace() error = %v", err)
+	}
+	want := Workspace{
+		ModFile:    filepath.Join(root, "go.mod"),
+		ModulePath: "example.com/mono",
+		WorkFile:   filepath.Join(root, "go.work"),
+	}
+	if ws != want {
+		t.Fatalf("FindWorkspace() = %+v, want %+v", ws, want)
+	}
+	importPath, subdir, err := ws.ImportPath(filepath.Join(root, "services", "billing"))
+	if err != nil || importPath != "example.com/mono/services/billing" || subdir != "services/billing" {
+		t.Fatalf("ImportPath() = %q, %q, %v", importPath, subdir, err)
+	}
+	if importPath, subdir, err := ws.ImportPath(root); err != nil || importPath != "example.com/mono" || subdir != "." {
+		t.Fatalf("ImportPath(root) = %q, %q, %v", importPath, subdir, err)
+	}
+
+	nested, err := FindWorkspace(filepath.Join(root, "tools", "lint", "cmd"))
+	if err != nil || nested.ModulePath != "example.com/mono/tools/lint" || nested.WorkFile != want.WorkFile {
+		t.Fatalf("FindWorkspace(nested) = %+v, %v", nested, err)
+	}
+
+	t.Setenv("GOWORK", "off")
+	if ws, err := FindWorkspace(filepath.Join(root, "services")); err != nil || ws.WorkFile != "" {
+		t.Fatalf("FindWorkspace() with GOWORK=off = %+v, %v", ws, err)
+	}
+
+	outside, err := FindWorkspace(t.TempDir())
+	if err != nil || outside != (Workspace{}) {
+		t.Fatalf("FindWorkspace(outside) = %+v, %v", outside, err)
+	}
+	if _, _, err := outside.ImportPath(root); err == nil || !strings.Contains(err.Error(), "no go.mod found") {
+		t.Fatalf("ImportPath() outside of a module error = %v", err)
+	}
+}
+
+func TestAddWorkUse(t *testing.T) {
+	root := t.TempDir()
+	writeTemplateTreeForTest(t, root, map[string]string{
+		"go.work":                 "go 1.22\n\nuse ./api\n",
+		"services/billing/go.mod": "module example.com/billing\n\ngo 1.26.0\n",
+	})
+	workFile := filepath.Join(root, "go.work")
+	moduleDir := filepath.Join(root, "services", "billing")
+	if uses, err := WorkUses(workFile, moduleDir); err != nil || uses {
+		t.Fatalf("WorkUses() before AddWorkUse = %v, %v", uses, err)
+	}
+
+	added, err := AddWorkUse(workFile, moduleDir)
+	if err != nil || !added {
+		t.Fatalf("AddWorkUse() = %v, %v", added, err)
+	}
+	want := "go 1.26.0\n\nuse (\n\t./api\n\t./services/billing\n)\n"
+	if got := readFileForAssertion(t, workFile); got != want {
+		t.Fatalf("go.work =\n%s\nwant\n%s", got, want)
+	}
+
+	if uses, err := WorkUses(workFile, moduleDir); err != nil || !uses {
+		t.Fatalf("WorkUses() after AddWorkUse = %v, %v", uses, err)
+	}
+	if added, err := AddWorkUse(workFile, moduleDir); err != nil || added {
+		t.Fatalf("AddWorkUse() second call = %v, %v, want already used", added, err)
+	}
+	if got := readFileForAssertion(t, workFile); got != want {
+		t.Fatalf("go.work changed by second call:\n%s", got)
+	}
+}
+
+func TestPlanNoModuleProject(t *testing.T) {
+	data := goCheckDataForTest()
+	data.ModuleName = "example.com/mono/services/check-web"
+	data.NoModule = true
+	data.ModuleSubdir = "services/check-web"
+
+	outputDir := filepath.Join(t.TempDir(), "check-web")
+	if err := Generate(outputDir, data); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	assertFileNotExists(t, filepath.Join(outputDir, "go.mod"))
+	dockerfile := readFileForAssertion(t, filepath.Join(outputDir, "Dockerfile"))
+	for _, want := range []string{
+		"#   docker build -f services/check-web/Dockerfile .\nFROM golang:",
+		"RUN make -C services/check-web build\n",
+		"COPY --from=builder /app/services/check-web/bin/check-web /app/check-web\n",
+	} {
+		if !strings.Contains(dockerfile, want) {
+			t.Fatalf("Dockerfile missing %q:\n%s", want, dockerfile)
+		}
+	}
+	mainGo := readFileForAssertion(t, filepath.Join(outputDir, "app", "main.go"))
+	if !strings.Contains(mainGo, `"example.com/mono/services/check-web/`) {
+		t.Fatalf("app/main.go does not import the project under the enclosing module:\n%s", mainGo)
+	}
+
+	lock, err := ReadLockfile(outputDir)
+	if err != nil || !lock.Data.NoModule || lock.Data.ModuleSubdir != "services/check-web" {
+		t.Fatalf("ReadLockfile() = %+v, %v", lock.Data, err)
+	}
+}
+
+func TestValidateModuleSubdir(t *testing.T) {
+	base := goCheckDataForTest()
+	for _, tt := range []struct {
+		noModule bool
+		subdir   string
+		wantErr  string
+	}{
+		{noModule: false, subdir: "services/api", wantErr: "only used with no-module"},
+		{noModule: true, subdir: "", wantErr: "invalid module subdirectory"},
+		{noModule: true, subdir: "../api", wantErr: "invalid module subdirectory"},
+		{noModule: true, subdir: "services//api", wantErr: "invalid module subdirectory"},
+		{noModule: true, subdir: "/services/api", wantErr: "invalid module subdirectory"},
+		{noModule: true, subdir: ".", wantErr: "is the module root"},
+		{noModule: true, subdir: "services/api"},
+	} {
+		data := base
+		data.NoModule = tt.noModule
+		data.ModuleSubdir = tt.subdir
+		err := data.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Validate(noModule=%v, subdir=%q) error = %v", tt.noModule, tt.subdir, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Validate(noModule=%v, subdir=%q) error = %v, want %q", tt.noModule, tt.subdir, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAddResourceToNoModuleProject(t *testing.T) {
+	data := goCheckDataForTest()
+	data.ModuleName = "example.com/mono/services/check-web"
+	data.MySQL = false
+	data.SQLite = true
+	data.NoModule = true
+	data.ModuleSubdir = "services/check-web"
+
+	projectDir := filepath.Join(t.TempDir(), "check-web")
+	if err := Generate(projectDir, data); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	result, err := AddResource(projectDir, ResourceSpec{
+		Name:   "order",
+		Fields: []ResourceField{{Name: "amount", Type: "int64"}},
+	})
+	if err != nil {
+		t.Fatalf("AddResource() error = %v", err)
+	}
+	if result.DB != "sqlite" {
+		t.Fatalf("AddResource() DB = %q, want sqlite", result.DB)
+	}
+	var imported bool
+	for _, relPath := range result.Created {
+		content, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(relPath)))
+		if err != nil {
+			t.Fatalf("read %s: %v", relPath, err)
+		}
+		imported = imported || strings.Contains(string(content), `"example.com/mono/services/check-web/internal/`)
+	}
+	if !imported {
+		t.Fatalf("resource files %v do not import the project under the enclosing module", result.Created)
+	}
+}
+
+func TestAddModuleRequirements(t *testing.T) {
+	root := t.TempDir()
+	modFile := filepath.Join(root, "go.mod")
+	writeTemplateTreeForTest(t, root, map[string]string{
+		"go.mod": "module example.com/mono\n\ngo 1.22\n\nrequire (\n" +
+			"\tgithub.com/google/uuid v1.0.0\n" +
+			"\tgithub.com/spf13/cobra v1.99.0\n" +
+			"\texample.com/internal/auth v1.2.3\n" +
+			")\n",
+	})
+
+	data := goCheckDataForTest()
+	data.GoVersion = "1.26.0"
+	data.NoModule = true
+	data.ModuleSubdir = "services/check-web"
+	before := readFileForAssertion(t, modFile)
+	planned, err := PlanModuleRequirements(modFile, data, Options{})
+	if err != nil {
+		t.Fatalf("PlanModuleRequirements() error = %v", err)
+	}
+	if got := readFileForAssertion(t, modFile); got != before {
+		t.Fatalf("PlanModuleRequirements() wrote go.mod:\n%s", got)
+	}
+	changed, err := AddModuleRequirements(modFile, data, Options{})
+	if err != nil {
+		t.Fatalf("AddModuleRequirements() error = %v", err)
+	}
+
+	goMod := readFileForAssertion(t, modFile)
+	for _, want := range []string{
+		"\ngo 1.26.0\n",
+		"\tgithub.com/google/uuid v1.6.0\n",
+		"\tgithub.com/spf13/cobra v1.99.0\n",
+		"\texample.com/internal/auth v1.2.3\n",
+		"\tgorm.io/gorm ",
+	} {
+		if !strings.Contains(goMod, want) {
+			t.Fatalf("go.mod missing %q:\n%s", want, goMod)
+		}
+	}
+	for _, req := range changed {
+		if strings.HasPrefix(req, "github.com/spf13/cobra ") {
+			t.Fatalf("AddModuleRequirements() downgraded %s", req)
+		}
+	}
+	if len(changed) == 0 || !strings.Contains(strings.Join(changed, "\n"), "github.com/google/uuid v1.6.0") {
+		t.Fatalf("AddModuleRequirements() changed = %q", changed)
+	}
+	if !reflect.DeepEqual(planned, changed) {
+		t.Fatalf("PlanModuleRequirements() = %q, AddModuleRequirements() = %q", planned, changed)
+	}
+
+	if again, err := AddModuleRequirements(modFile, data, Options{}); err != nil || len(again) != 0 {
+		t.Fatalf("AddModuleRequirements() second call = %q, %v", again, err)
+	}
+}